@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"logistics-backend/internal/config"
+	"logistics-backend/internal/database"
+	"logistics-backend/internal/migrations"
+)
+
+const usage = `Usage: migrate <command> [args]
+
+Commands:
+  up            apply all pending migrations
+  down [n]      revert the last n migrations (default 1)
+  status        list migrations and whether they are applied
+  create <name> write a new empty migration pair per dialect below -dir
+`
+
+func main() {
+	dir := flag.String("dir", migrations.Dir, "directory new migrations are created in (create only)")
+	configFile := flag.String("config", "", "config file (.yaml, .toml, .json or .env)")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// create only touches the source tree, so it needs no database
+	if args[0] == "create" {
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		paths, err := migrations.Create(*dir, args[1])
+		for _, p := range paths {
+			fmt.Println("created", p)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	var configArgs []string
+	if *configFile != "" {
+		configArgs = []string{"--config", *configFile}
+	}
+	// Only the database settings matter here; the server's are not checked.
+	cfg, err := config.Read(configArgs)
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if err := cfg.ValidateDatabase(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	db, dialect, err := database.Open(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	m, err := migrations.New(db, dialect)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, mig := range applied {
+			fmt.Printf("applied  %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("nothing to apply")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatalf("invalid step count %q", args[1])
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		for _, mig := range reverted {
+			fmt.Printf("reverted %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, state)
+		}
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -1,58 +1,126 @@
 package main
 
 import (
-	"logistics-backend/internal/auth"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"logistics-backend/internal/blob"
 	"logistics-backend/internal/config"
-	"logistics-backend/internal/customers"
 	"logistics-backend/internal/database"
-	"logistics-backend/internal/drivers"
-	"logistics-backend/internal/middleware"
-	"logistics-backend/internal/shipments"
-	"logistics-backend/internal/warehouses"
-
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
+	"logistics-backend/internal/geocode"
+	"logistics-backend/internal/migrations"
+	"logistics-backend/internal/pricing"
+	"logistics-backend/internal/server"
+	"logistics-backend/internal/store"
+	"logistics-backend/internal/store/memory"
+	"logistics-backend/internal/store/sqlstore"
+	"logistics-backend/internal/tracking"
 )
 
 func main() {
-	config.LoadConfig()
-	database.Connect()
-
-	r := gin.Default()
-
-	// CORS middleware
-	config := cors.DefaultConfig()
-	config.AllowOrigins = []string{
-		"http://localhost:5173",
-		"http://192.168.1.14:5173",
-	}
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
-	r.Use(cors.New(config))
-
-	// Public routes
-	r.POST("/auth/register", auth.Register)
-	r.POST("/auth/login", auth.Login)
-
-	// Protected routes
-	api := r.Group("/api")
-	{
-		api.POST("/shipments", middleware.AuthMiddleware("manager"), shipments.CreateShipment)
-		api.GET("/shipments", middleware.AuthMiddleware("customer", "manager", "driver"), shipments.GetShipments)
-		api.GET("/getWarehouses", middleware.AuthMiddleware("manager", "driver"), warehouses.GetWarehouses)
-		api.GET("/getDrivers", middleware.AuthMiddleware("manager"), drivers.GetDrivers)
-		api.GET("/getCustomers", middleware.AuthMiddleware("manager"), customers.GetCustomers)
-		api.PUT("/shipments/:id/status", middleware.AuthMiddleware("manager", "driver"), shipments.UpdateShipmentStatus)
-		api.PUT("/shipments/:id/assign", middleware.AuthMiddleware("manager"), shipments.AssignShipmentToCourier)
-		api.GET("/me", middleware.AuthMiddleware(), auth.GetUserDetails)
-		api.GET("/getShipments/:id", middleware.AuthMiddleware("customer", "manager", "driver"), shipments.GetShipmentData)
-		api.GET("/getShipmentCoordinates", middleware.AuthMiddleware("customer", "manager", "driver"), shipments.GetShipmentCoordinates)
-		api.GET("/getShipmentCoordinatesById/:id", middleware.AuthMiddleware("customer", "manager", "driver"), shipments.GetShipmentCoordinatesByShipmentID)
-	}
-
-	// for _, ri := range r.Routes() {
-	// 	println(ri.Method, ri.Path)
-	// }
-
-	r.Run(":8080")
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	var deps server.Deps
+	if cfg.DBDriver == "memory" {
+		deps.Stores = memory.New()
+		seedWarehouses(deps.Stores.Warehouses)
+		log.Println("⚠️  Using in-memory store, data will not be persisted")
+	} else {
+		db, dialect, err := database.Open(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+		log.Printf("✅ Connected to %s", dialect)
+
+		migrator, err := migrations.New(db, dialect)
+		if err != nil {
+			log.Fatal("Loading migrations failed:", err)
+		}
+		if cfg.MigrateOnStart {
+			migrate(migrator)
+		}
+		deps.Stores = sqlstore.New(db, dialect)
+		deps.DB = db
+		deps.Schema = migrator
+	}
+
+	// blob_store is "local", the only backend config.Validate accepts.
+	deps.Blobs, err = blob.NewLocal(cfg.BlobDir)
+	if err != nil {
+		log.Fatal("Opening blob store failed:", err)
+	}
+
+	if cfg.Geocoder == "gazetteer" {
+		gazetteer, err := geocode.LoadGazetteer(cfg.GazetteerFile)
+		if err != nil {
+			log.Fatal("Loading gazetteer failed:", err)
+		}
+		deps.Geocoder = geocode.NewCache(gazetteer, cfg.GeocodeCacheSize)
+	}
+
+	deps.Rates, err = pricing.LoadRateCard(cfg.RateCardFile)
+	if err != nil {
+		log.Fatal("Loading rate card failed:", err)
+	}
+
+	// Already checked by config.Validate.
+	trackingFormat, _ := tracking.ParseFormat(cfg.TrackingFormat, cfg.TrackingCheckDigit)
+	trackingFormat, _ = trackingFormat.WithLegacy(cfg.TrackingLegacyPattern)
+
+	handler, err := server.New(server.Config{
+		JWTSecret:      cfg.JWTSecret,
+		AccessTokenTTL: cfg.AccessTokenTTL,
+		AllowOrigins:   cfg.CORSOrigins,
+		TrustedProxies: cfg.TrustedProxies,
+		RequestTimeout: cfg.RequestTimeout,
+		ExportTimeout:  cfg.ExportTimeout,
+		TrackingFormat: trackingFormat,
+		TrackRateLimit: cfg.TrackRateLimit,
+		TrackRateBurst: cfg.TrackRateBurst,
+
+		MaxDeliveryAttempts: cfg.MaxDeliveryAttempts,
+	}, deps)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := server.ListenAndServe(ctx, cfg.ListenAddr, handler, cfg.ShutdownTimeout); err != nil {
+		log.Fatal("Server error:", err)
+	}
+}
+
+func migrate(m *migrations.Migrator) {
+	applied, err := m.Up(context.Background())
+	if err != nil {
+		log.Fatal("Migration failed:", err)
+	}
+	for _, mig := range applied {
+		log.Printf("Applied migration %04d_%s", mig.Version, mig.Name)
+	}
+}
+
+// seedWarehouses gives an in-memory store the same hubs the simulator creates,
+// so shipments can be created without a database.
+func seedWarehouses(ws store.WarehouseStore) {
+	hubs := []store.Warehouse{
+		{Name: "Delhi Hub", Code: "DEL", Latitude: 28.6139, Longitude: 77.2090},
+		{Name: "Mumbai Hub", Code: "BOM", Latitude: 19.0760, Longitude: 72.8777},
+		{Name: "Bengaluru Hub", Code: "BLR", Latitude: 12.9716, Longitude: 77.5946},
+		{Name: "Kolkata Hub", Code: "CCU", Latitude: 22.5726, Longitude: 88.3639},
+	}
+	for i := range hubs {
+		if err := ws.Create(context.Background(), &hubs[i]); err != nil {
+			log.Fatal("Seeding warehouses failed:", err)
+		}
+	}
 }
@@ -1,9 +1,8 @@
 package auth
 
 import (
-	"database/sql"
-	"logistics-backend/internal/config"
-	"logistics-backend/internal/database"
+	"errors"
+	"logistics-backend/internal/store"
 	"net/http"
 	"time"
 
@@ -12,15 +11,17 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-type User struct {
-	ID       int64  `json:"id"`
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"-"`
-	Role     string `json:"role"`
+type Handler struct {
+	Users     store.UserStore
+	JWTSecret string
+	TokenTTL  time.Duration
 }
 
-func Register(c *gin.Context) {
+func NewHandler(users store.UserStore, jwtSecret string, tokenTTL time.Duration) *Handler {
+	return &Handler{Users: users, JWTSecret: jwtSecret, TokenTTL: tokenTTL}
+}
+
+func (h *Handler) Register(c *gin.Context) {
 	var input struct {
 		Name     string `json:"name"`
 		Email    string `json:"email"`
@@ -33,11 +34,16 @@ func Register(c *gin.Context) {
 	}
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(input.Password), 12)
-	_, err := database.DB.Exec(
-		"INSERT INTO users (name, email, password_hash, role) VALUES (?, ?, ?, ?)",
-		input.Name, input.Email, string(hashedPassword), input.Role,
-	)
-	if err != nil {
+	err := h.Users.Create(c.Request.Context(), &store.User{
+		Name:         input.Name,
+		Email:        input.Email,
+		PasswordHash: string(hashedPassword),
+		Role:         input.Role,
+	})
+	if errors.Is(err, store.ErrDuplicate) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "User creation failed"})
 		return
 	}
@@ -45,7 +51,7 @@ func Register(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
 }
 
-func Login(c *gin.Context) {
+func (h *Handler) Login(c *gin.Context) {
 	var input struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
@@ -55,13 +61,8 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	var user User
-	err := database.DB.QueryRow(
-		"SELECT id, name, email, password_hash, role FROM users WHERE email = ?",
-		input.Email,
-	).Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.Role)
-
-	if err == sql.ErrNoRows {
+	user, err := h.Users.GetByEmail(c.Request.Context(), input.Email)
+	if errors.Is(err, store.ErrNotFound) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	} else if err != nil {
@@ -69,7 +70,7 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)) != nil {
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password)) != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
@@ -77,28 +78,23 @@ func Login(c *gin.Context) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": user.ID,
 		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 72).Unix(),
+		"exp":     time.Now().Add(h.TokenTTL).Unix(),
 	})
 
-	tokenString, _ := token.SignedString([]byte(config.AppConfig.JWTSecret))
+	tokenString, _ := token.SignedString([]byte(h.JWTSecret))
 
 	c.JSON(http.StatusOK, gin.H{"token": tokenString})
 }
 
-func GetUserDetails(c *gin.Context) {
+func (h *Handler) GetUserDetails(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
 		return
 	}
 
-	var user User
-	err := database.DB.QueryRow(
-		"SELECT id, name, email, role FROM users WHERE id = ?",
-		userID,
-	).Scan(&user.ID, &user.Name, &user.Email, &user.Role)
-
-	if err == sql.ErrNoRows {
+	user, err := h.Users.GetByID(c.Request.Context(), int64(userID.(int)))
+	if errors.Is(err, store.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	} else if err != nil {
@@ -0,0 +1,29 @@
+// Package blob stores uploaded files, such as proof-of-delivery images,
+// outside the database. Files are addressed by slash-separated keys.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("blob: not found")
+	ErrInvalidKey = errors.New("blob: invalid key")
+)
+
+// Store keeps files by key. Put replaces any file already at the key.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey accepts clean, relative keys that cannot escape the store.
+func validKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "/") && path.Clean(key) == key &&
+		key != ".." && !strings.HasPrefix(key, "../") && !strings.ContainsRune(key, '\\')
+}
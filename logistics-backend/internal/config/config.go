@@ -1,46 +1,433 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
 
+	"logistics-backend/internal/tracking"
+
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
+	// ListenAddr is the host:port the HTTP server binds to.
+	ListenAddr string
+	// CORSOrigins are the browser origins allowed to call the API.
+	CORSOrigins []string
+	// TrustedProxies may set X-Forwarded-For; other clients are identified
+	// by their remote address.
+	TrustedProxies []string
+
+	DBDriver   string
 	DBUser     string
 	DBPassword string
 	DBHost     string
 	DBPort     string
 	DBName     string
+	DBPath     string
+	DBSSLMode  string
 	JWTSecret  string
+
+	// AccessTokenTTL is how long a login token stays valid.
+	AccessTokenTTL time.Duration
+	// RequestTimeout bounds each API request; ShutdownTimeout bounds the
+	// drain of in-flight requests on SIGTERM.
+	RequestTimeout  time.Duration
+	ShutdownTimeout time.Duration
+	// ExportTimeout replaces RequestTimeout for shipment exports, which
+	// stream whole tables.
+	ExportTimeout time.Duration
+
+	// Connection pool limits, see database/sql.DB.
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+
+	// MigrateOnStart applies pending schema migrations when the server boots.
+	MigrateOnStart bool
+
+	// TrackingFormat and TrackingCheckDigit lay out generated tracking
+	// numbers, see tracking.ParseFormat.
+	TrackingFormat     string
+	TrackingCheckDigit string
+	// TrackingLegacyPattern matches the tracking numbers of shipments booked
+	// before numbers were generated, which public tracking still looks up.
+	TrackingLegacyPattern string
+	// TrackRateLimit and TrackRateBurst limit public tracking lookups per
+	// client IP, in requests per minute.
+	TrackRateLimit int
+	TrackRateBurst int
+
+	// MaxDeliveryAttempts is the number of failed delivery attempts after
+	// which a shipment returns to origin.
+	MaxDeliveryAttempts int
+
+	// BlobStore names the backend for uploaded files; "local" keeps them
+	// under BlobDir.
+	BlobStore string
+	BlobDir   string
+
+	// Geocoder names the backend that places destination addresses:
+	// "gazetteer" looks them up offline in GazetteerFile, or in the bundled
+	// gazetteer when that is empty; "none" disables geocoding.
+	// GeocodeCacheSize is the number of addresses whose answers are kept.
+	Geocoder         string
+	GazetteerFile    string
+	GeocodeCacheSize int
+
+	// RateCardFile is the YAML rate card shipments are priced by; empty
+	// means the bundled one.
+	RateCardFile string
 }
 
-var AppConfig *Config
+// setting describes one configuration key. Keys are lower snake case in
+// config files, upper snake case in the environment and kebab case as flags.
+type setting struct {
+	key   string
+	def   any
+	usage string
+	// flag exposes the key on the command line. Secrets stay off it so they
+	// do not leak into process listings.
+	flag bool
+}
+
+var settings = []setting{
+	{"listen_addr", ":8080", "address the HTTP server listens on", true},
+	{"cors_origins", "http://localhost:5173", "comma-separated browser origins allowed by CORS", true},
+	{"trusted_proxies", "", "comma-separated proxy addresses or CIDRs trusted to set X-Forwarded-For", true},
+	{"db_driver", "mysql", "database backend: mysql, postgres, sqlite or memory", true},
+	{"db_user", "", "database user", true},
+	{"db_password", "", "database password", false},
+	{"db_host", "localhost", "database host", true},
+	{"db_port", "", "database port (defaults to 3306 for mysql, 5432 for postgres)", true},
+	{"db_name", "logistics_db", "database name", true},
+	{"db_path", "logistics.db", "SQLite database file", true},
+	{"db_sslmode", "disable", "PostgreSQL sslmode", true},
+	{"jwt_secret", "", "secret used to sign access tokens (at least 32 bytes)", false},
+	{"access_token_ttl", 72 * time.Hour, "lifetime of access tokens", true},
+	{"request_timeout", 15 * time.Second, "deadline for each API request", true},
+	{"shutdown_timeout", 30 * time.Second, "time allowed to drain requests on shutdown", true},
+	{"export_timeout", 10 * time.Minute, "deadline for each shipment export", true},
+	{"db_max_open_conns", 25, "maximum open database connections", true},
+	{"db_max_idle_conns", 25, "maximum idle database connections", true},
+	{"db_conn_max_lifetime", 5 * time.Minute, "maximum lifetime of a database connection", true},
+	{"db_conn_max_idle_time", time.Minute, "maximum idle time of a database connection", true},
+	{"migrate_on_start", false, "apply pending migrations at startup", true},
+	{"tracking_format", "LG{warehouse}{seq:8}{check}", "tracking number template with {warehouse}, {seq:N} and {check}", true},
+	{"tracking_check_digit", tracking.Mod11, "tracking number check digit: mod10, mod11 or none", true},
+	{"tracking_legacy_pattern", "TRK-[0-9]+", "regular expression for tracking numbers entered before generation (empty for none)", true},
+	{"track_rate_limit", 30, "public tracking lookups allowed per client IP per minute", true},
+	{"track_rate_burst", 10, "burst size for public tracking lookups", true},
+	{"max_delivery_attempts", 3, "failed delivery attempts before a shipment returns to origin", true},
+	{"blob_store", "local", "storage for uploaded files such as proof-of-delivery images: local", true},
+	{"blob_dir", "data/blobs", "directory of the local blob store", true},
+	{"geocoder", "gazetteer", "geocoder for destination addresses: gazetteer or none", true},
+	{"gazetteer_file", "", "PIN-code gazetteer CSV (defaults to the bundled one)", true},
+	{"geocode_cache_size", 10000, "number of geocoded addresses kept in memory", true},
+	{"rate_card_file", "", "YAML rate card for shipment pricing (defaults to the bundled one)", true},
+}
 
-func LoadConfig() {
-	viper.SetConfigFile(".env")
-	err := viper.ReadInConfig()
+// Load resolves the configuration from, in increasing priority: built-in
+// defaults, a config file (YAML, TOML, JSON or .env), environment variables
+// and the command-line flags in args.
+//
+// The file is taken from --config or CONFIG_FILE; without either, ./.env is
+// read if it exists. The result is validated and every problem is reported
+// in the returned error.
+func Load(args []string) (*Config, error) {
+	cfg, err := Read(args)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Read resolves the configuration like Load but leaves validation to the
+// caller, for tools that only use part of it.
+func Read(args []string) (*Config, error) {
+	v := viper.New()
+	fs := pflag.NewFlagSet("logistics-backend", pflag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "config file (.yaml, .toml, .json or .env)")
+
+	for _, s := range settings {
+		v.SetDefault(s.key, s.def)
+		if !s.flag {
+			continue
+		}
+		name := strings.ReplaceAll(s.key, "_", "-")
+		switch def := s.def.(type) {
+		case string:
+			fs.String(name, def, s.usage)
+		case int:
+			fs.Int(name, def, s.usage)
+		case bool:
+			fs.Bool(name, def, s.usage)
+		case time.Duration:
+			fs.Duration(name, def, s.usage)
+		}
+		if err := v.BindPFlag(s.key, fs.Lookup(name)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
 	}
 
-	AppConfig = &Config{
-		DBUser:     viper.GetString("DB_USER"),
-		DBPassword: viper.GetString("DB_PASSWORD"),
-		DBHost:     viper.GetString("DB_HOST"),
-		DBPort:     viper.GetString("DB_PORT"),
-		DBName:     viper.GetString("DB_NAME"),
-		JWTSecret:  viper.GetString("JWT_SECRET"),
+	if *configFile != "" {
+		v.SetConfigFile(*configFile)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("reading config file %s: %w", *configFile, err)
+		}
+	} else if _, err := os.Stat(".env"); err == nil {
+		v.SetConfigFile(".env")
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("reading .env: %w", err)
+		}
 	}
+
+	v.AutomaticEnv()
+
+	cfg := &Config{
+		ListenAddr:     v.GetString("listen_addr"),
+		CORSOrigins:    stringList(v.Get("cors_origins")),
+		TrustedProxies: stringList(v.Get("trusted_proxies")),
+
+		DBDriver:   v.GetString("db_driver"),
+		DBUser:     v.GetString("db_user"),
+		DBPassword: v.GetString("db_password"),
+		DBHost:     v.GetString("db_host"),
+		DBPort:     v.GetString("db_port"),
+		DBName:     v.GetString("db_name"),
+		DBPath:     v.GetString("db_path"),
+		DBSSLMode:  v.GetString("db_sslmode"),
+		JWTSecret:  v.GetString("jwt_secret"),
+
+		AccessTokenTTL:  v.GetDuration("access_token_ttl"),
+		RequestTimeout:  v.GetDuration("request_timeout"),
+		ShutdownTimeout: v.GetDuration("shutdown_timeout"),
+		ExportTimeout:   v.GetDuration("export_timeout"),
+
+		DBMaxOpenConns:    v.GetInt("db_max_open_conns"),
+		DBMaxIdleConns:    v.GetInt("db_max_idle_conns"),
+		DBConnMaxLifetime: v.GetDuration("db_conn_max_lifetime"),
+		DBConnMaxIdleTime: v.GetDuration("db_conn_max_idle_time"),
+
+		MigrateOnStart: v.GetBool("migrate_on_start"),
+
+		TrackingFormat:        v.GetString("tracking_format"),
+		TrackingCheckDigit:    v.GetString("tracking_check_digit"),
+		TrackingLegacyPattern: v.GetString("tracking_legacy_pattern"),
+		TrackRateLimit:        v.GetInt("track_rate_limit"),
+		TrackRateBurst:        v.GetInt("track_rate_burst"),
+
+		MaxDeliveryAttempts: v.GetInt("max_delivery_attempts"),
+
+		BlobStore: v.GetString("blob_store"),
+		BlobDir:   v.GetString("blob_dir"),
+
+		Geocoder:         v.GetString("geocoder"),
+		GazetteerFile:    v.GetString("gazetteer_file"),
+		GeocodeCacheSize: v.GetInt("geocode_cache_size"),
+
+		RateCardFile: v.GetString("rate_card_file"),
+	}
+
+	if cfg.DBPort == "" {
+		switch cfg.DBDriver {
+		case "mysql":
+			cfg.DBPort = "3306"
+		case "postgres":
+			cfg.DBPort = "5432"
+		}
+	}
+
+	return cfg, nil
 }
 
-func GetDSN() string {
+// stringList accepts either a list (from YAML/TOML/JSON) or a
+// comma-separated string (from .env, the environment or a flag).
+func stringList(raw any) []string {
+	var parts []string
+	switch val := raw.(type) {
+	case string:
+		parts = strings.Split(val, ",")
+	case []string:
+		parts = val
+	case []any:
+		for _, p := range val {
+			parts = append(parts, fmt.Sprint(p))
+		}
+	}
+
+	var out []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Validate checks the whole configuration and joins every problem found
+// into one error.
+func (c *Config) Validate() error {
+	errs := c.databaseErrors()
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.ListenAddr == "" {
+		add("listen_addr must not be empty")
+	}
+	for _, origin := range c.CORSOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			add("cors_origins: %q is not an origin like https://example.com", origin)
+		}
+	}
+
+	if c.JWTSecret == "" {
+		add("jwt_secret is required")
+	} else if len(c.JWTSecret) < 32 {
+		add("jwt_secret must be at least 32 bytes, got %d", len(c.JWTSecret))
+	}
+
+	if c.AccessTokenTTL <= 0 {
+		add("access_token_ttl must be positive")
+	}
+	if c.RequestTimeout < 0 {
+		add("request_timeout must not be negative")
+	}
+	if c.ExportTimeout < 0 {
+		add("export_timeout must not be negative")
+	}
+	if c.ShutdownTimeout <= 0 {
+		add("shutdown_timeout must be positive")
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				add("trusted_proxies: %q is neither an IP address nor a CIDR", proxy)
+			}
+		}
+	}
+	if c.TrackRateLimit <= 0 || c.TrackRateBurst <= 0 {
+		add("track_rate_limit and track_rate_burst must be positive")
+	}
+	if f, err := tracking.ParseFormat(c.TrackingFormat, c.TrackingCheckDigit); err != nil {
+		add("tracking_format: %v", err)
+	} else if _, err := f.WithLegacy(c.TrackingLegacyPattern); err != nil {
+		add("tracking_legacy_pattern: %v", err)
+	}
+	if c.MaxDeliveryAttempts <= 0 {
+		add("max_delivery_attempts must be positive")
+	}
+	switch c.BlobStore {
+	case "local":
+		if c.BlobDir == "" {
+			add("blob_dir is required for the local blob store")
+		}
+	default:
+		add("blob_store must be local, got %q", c.BlobStore)
+	}
+	switch c.Geocoder {
+	case "gazetteer", "none":
+	default:
+		add("geocoder must be gazetteer or none, got %q", c.Geocoder)
+	}
+	if c.GeocodeCacheSize < 0 {
+		add("geocode_cache_size must not be negative")
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateDatabase checks only the settings needed to connect to the
+// database, which is all the migrate command uses.
+func (c *Config) ValidateDatabase() error {
+	return errors.Join(c.databaseErrors()...)
+}
+
+func (c *Config) databaseErrors() []error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch c.DBDriver {
+	case "mysql", "postgres":
+		if c.DBHost == "" {
+			add("db_host is required for %s", c.DBDriver)
+		}
+		if c.DBPort == "" {
+			add("db_port is required for %s", c.DBDriver)
+		}
+		if c.DBUser == "" {
+			add("db_user is required for %s", c.DBDriver)
+		}
+		if c.DBName == "" {
+			add("db_name is required for %s", c.DBDriver)
+		}
+	case "sqlite":
+		if c.DBPath == "" {
+			add("db_path is required for sqlite")
+		}
+	case "memory":
+	default:
+		add("db_driver %q is not one of mysql, postgres, sqlite, memory", c.DBDriver)
+	}
+
+	if c.DBMaxOpenConns < 0 {
+		add("db_max_open_conns must not be negative")
+	}
+	if c.DBMaxIdleConns < 0 {
+		add("db_max_idle_conns must not be negative")
+	}
+	if c.DBMaxOpenConns > 0 && c.DBMaxIdleConns > c.DBMaxOpenConns {
+		add("db_max_idle_conns (%d) must not exceed db_max_open_conns (%d)", c.DBMaxIdleConns, c.DBMaxOpenConns)
+	}
+	if c.DBConnMaxLifetime < 0 || c.DBConnMaxIdleTime < 0 {
+		add("db_conn_max_lifetime and db_conn_max_idle_time must not be negative")
+	}
+	return errs
+}
+
+// DSN builds the connection string for the configured driver.
+func (c *Config) DSN() string {
+	switch c.DBDriver {
+	case "sqlite":
+		return fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite",
+			c.DBPath)
+	case "postgres":
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(c.DBUser, c.DBPassword),
+			Host:     c.DBHost + ":" + c.DBPort,
+			Path:     "/" + c.DBName,
+			RawQuery: url.Values{"sslmode": {c.DBSSLMode}}.Encode(),
+		}
+		return dsn.String()
+	}
 	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
-		AppConfig.DBUser,
-		AppConfig.DBPassword,
-		AppConfig.DBHost,
-		AppConfig.DBPort,
-		AppConfig.DBName,
+		c.DBUser,
+		c.DBPassword,
+		c.DBHost,
+		c.DBPort,
+		c.DBName,
 	)
 }
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadLayers(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(file, []byte(`
+listen_addr: ":9000"
+request_timeout: 20s
+db_name: from_file
+db_user: file_user
+cors_origins:
+  - https://app.example.com
+  - https://admin.example.com
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("LISTEN_ADDR", ":9100")
+	t.Setenv("DB_NAME", "from_env")
+
+	cfg, err := Read([]string{"--config", file, "--listen-addr", ":9200"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key       string
+		got, want any
+	}{
+		// Defaults.
+		{"db_driver", cfg.DBDriver, "mysql"},
+		{"db_port", cfg.DBPort, "3306"},
+		{"shutdown_timeout", cfg.ShutdownTimeout, 30 * time.Second},
+		// The file over defaults.
+		{"request_timeout", cfg.RequestTimeout, 20 * time.Second},
+		{"db_user", cfg.DBUser, "file_user"},
+		// The environment over the file.
+		{"db_name", cfg.DBName, "from_env"},
+		// Flags over everything.
+		{"listen_addr", cfg.ListenAddr, ":9200"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.key, tt.got, tt.want)
+		}
+	}
+	if want := []string{"https://app.example.com", "https://admin.example.com"}; !slices.Equal(cfg.CORSOrigins, want) {
+		t.Errorf("cors_origins = %v, want %v", cfg.CORSOrigins, want)
+	}
+}
+
+func TestReadDefaults(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	cfg, err := Read(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cfg.CORSOrigins, []string{"http://localhost:5173"}) {
+		t.Errorf("default cors_origins = %v", cfg.CORSOrigins)
+	}
+	// Comma-separated lists come from the environment.
+	t.Setenv("CORS_ORIGINS", "https://a.example.com, https://b.example.com")
+	if cfg, err = Read(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cfg.CORSOrigins, []string{"https://a.example.com", "https://b.example.com"}) {
+		t.Errorf("cors_origins from the environment = %v", cfg.CORSOrigins)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	cfg, err := Read(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.DBUser = "root"
+	cfg.JWTSecret = strings.Repeat("s", 32)
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("defaults with a user and secret: %v", err)
+	}
+
+	cfg.ListenAddr = ""
+	cfg.CORSOrigins = []string{"example.com"}
+	cfg.JWTSecret = "short"
+	cfg.DBDriver = "oracle"
+	cfg.TrackRateLimit = 0
+	cfg.TrackingLegacyPattern = "TRK-["
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate accepted a broken configuration")
+	}
+	for _, want := range []string{"listen_addr", "cors_origins", "jwt_secret", "db_driver", "track_rate_limit", "tracking_legacy_pattern"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s:\n%v", want, err)
+		}
+	}
+
+	// The migrate command only cares about the database.
+	if err := cfg.ValidateDatabase(); err == nil || strings.Contains(err.Error(), "jwt_secret") {
+		t.Errorf("ValidateDatabase = %v, want the db_driver problem alone", err)
+	}
+}
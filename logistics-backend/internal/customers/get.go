@@ -1,19 +1,21 @@
 package customers
 
 import (
-	"logistics-backend/internal/database"
+	"logistics-backend/internal/store"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-type Customer struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+type Handler struct {
+	Users store.UserStore
 }
 
-func GetCustomers(c *gin.Context) {
+func NewHandler(users store.UserStore) *Handler {
+	return &Handler{Users: users}
+}
+
+func (h *Handler) GetCustomers(c *gin.Context) {
 	role := c.GetString("role")
 
 	// Only managers should be able to view customers
@@ -22,28 +24,12 @@ func GetCustomers(c *gin.Context) {
 		return
 	}
 
-	rows, err := database.DB.Query("SELECT id, name, email FROM users")
+	customers, err := h.Users.List(c.Request.Context(), "")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch customers",
 			"details": err.Error()})
 		return
 	}
-	defer rows.Close()
-
-	var customers []Customer
-	for rows.Next() {
-		var cust Customer
-		if err := rows.Scan(&cust.ID, &cust.Name, &cust.Email); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan customer data"})
-			return
-		}
-		customers = append(customers, cust)
-	}
-
-	if err := rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading rows"})
-		return
-	}
 
 	c.JSON(http.StatusOK, customers)
 }
@@ -2,26 +2,45 @@ package database
 
 import (
 	"database/sql"
-	"log"
+	"fmt"
 
 	"logistics-backend/internal/config"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
 )
 
-var DB *sql.DB
-
-func Connect() {
-	var err error
-	DB, err = sql.Open("mysql", config.GetDSN())
+// Open connects to the database described by cfg and checks that it is
+// reachable.
+func Open(cfg *config.Config) (*sql.DB, Dialect, error) {
+	dialect, err := ParseDialect(cfg.DBDriver)
 	if err != nil {
-		log.Fatal("Database connection failed:", err)
+		return nil, "", err
 	}
 
-	err = DB.Ping()
+	db, err := sql.Open(dialect.DriverName(), cfg.DSN())
 	if err != nil {
-		log.Fatal("Database not reachable:", err)
+		return nil, "", fmt.Errorf("database connection failed: %w", err)
+	}
+
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+
+	if dialect == SQLite {
+		// SQLite allows a single writer; sharing one connection avoids
+		// SQLITE_BUSY and keeps ":memory:" databases alive between queries.
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxLifetime(0)
+		db.SetConnMaxIdleTime(0)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, "", fmt.Errorf("database not reachable: %w", err)
 	}
 
-	log.Println("✅ Connected to MySQL")
+	return db, dialect, nil
 }
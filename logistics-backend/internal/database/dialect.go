@@ -0,0 +1,121 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect names a supported SQL backend and hides the few places where
+// their SQL differs. Queries are written with MySQL-style "?" placeholders
+// and passed through Rebind before execution.
+type Dialect string
+
+const (
+	MySQL    Dialect = "mysql"
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+func ParseDialect(name string) (Dialect, error) {
+	switch d := Dialect(name); d {
+	case MySQL, SQLite, Postgres:
+		return d, nil
+	}
+	return "", fmt.Errorf("unsupported database driver %q", name)
+}
+
+// DriverName is the database/sql driver registered for the dialect.
+func (d Dialect) DriverName() string {
+	if d == Postgres {
+		return "pgx"
+	}
+	return string(d)
+}
+
+// Rebind rewrites "?" placeholders into the dialect's native form.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// SupportsLastInsertID reports whether sql.Result.LastInsertId works. When it
+// does not, inserts must use a RETURNING clause instead.
+func (d Dialect) SupportsLastInsertID() bool {
+	return d != Postgres
+}
+
+// TransactionalDDL reports whether schema changes can be rolled back. MySQL
+// commits implicitly on every DDL statement.
+func (d Dialect) TransactionalDDL() bool {
+	return d != MySQL
+}
+
+// Now is the SQL expression for the current UTC time.
+func (d Dialect) Now() string {
+	switch d {
+	case SQLite:
+		return "CURRENT_TIMESTAMP"
+	case Postgres:
+		return "NOW()"
+	}
+	return "UTC_TIMESTAMP()"
+}
+
+// Time converts t into a query argument that compares correctly with
+// timestamp columns. SQLite stores CURRENT_TIMESTAMP as "YYYY-MM-DD HH:MM:SS"
+// text, so arguments have to be formatted the same way.
+func (d Dialect) Time(t time.Time) any {
+	if d == SQLite {
+		return t.UTC().Format(time.DateTime)
+	}
+	return t.UTC()
+}
+
+// FoldEqual is a case-insensitive "column = ?" comparison. MySQL's default
+// collation already ignores case; the other backends compare bytes.
+func (d Dialect) FoldEqual(column string) string {
+	if d == MySQL {
+		return column + " = ?"
+	}
+	return "LOWER(" + column + ") = LOWER(?)"
+}
+
+// IsDuplicate reports whether err is a unique-constraint violation.
+func (d Dialect) IsDuplicate(err error) bool {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1062
+	}
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE ||
+			liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+// Package eta estimates when a shipment in transit will reach its
+// destination from the positions reported along the way.
+package eta
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"logistics-backend/internal/geo"
+	"logistics-backend/internal/store"
+)
+
+const (
+	// Samples is the number of latest located events Compute needs; of
+	// those, speed is observed over the ones within window of the last.
+	Samples = 20
+	window  = 2 * time.Hour
+	// minSegment skips legs too short in time to give a meaningful speed,
+	// such as a piece scan and the shipment event it rolls up to.
+	minSegment = time.Minute
+
+	// fallbackSpeedKmh is assumed until a speed has been observed, with
+	// fallbackSpread either side of it.
+	fallbackSpeedKmh = 30
+	fallbackSpread   = 0.5
+	// Observed speeds are floored at minSpeedKmh, so a parked van does not
+	// push the ETA to infinity, and their spread is kept within bounds.
+	minSpeedKmh = 5
+	minSpread   = 0.1
+	maxSpread   = 0.6
+)
+
+type Range struct {
+	Earliest time.Time `json:"earliest"`
+	Latest   time.Time `json:"latest"`
+}
+
+// Estimate is an expected arrival time and the range it likely falls in.
+type Estimate struct {
+	ETA         time.Time `json:"eta"`
+	Range       Range     `json:"eta_range"`
+	RemainingKm float64   `json:"remaining_km"`
+	SpeedKmh    float64   `json:"speed_kmh"`
+	// Observed is false while the speed is the fallback assumption.
+	Observed bool `json:"speed_observed"`
+}
+
+type sample struct {
+	at geo.Point
+	ts time.Time
+}
+
+// Compute estimates the arrival at dest from events as listed by
+// store.EventStore. It returns nil when no event has a position. Scans are
+// placed at the time they were made rather than when they reached the
+// server, since a device that was offline uploads them late. Times that
+// have already passed are moved up to now.
+func Compute(dest geo.Point, events []store.ShipmentEvent, now time.Time) *Estimate {
+	var samples []sample
+	for _, e := range events {
+		if e.Latitude == nil || e.Longitude == nil {
+			continue
+		}
+		ts := e.Timestamp
+		if e.ScannedAt != nil {
+			ts = *e.ScannedAt
+		}
+		samples = append(samples, sample{geo.Point{Lat: *e.Latitude, Lng: *e.Longitude}, ts})
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].ts.Before(samples[j].ts) })
+	last := samples[len(samples)-1]
+	samples = recent(samples)
+
+	est := &Estimate{RemainingKm: geo.Distance(last.at, dest)}
+	speed, spread, ok := observedSpeed(samples)
+	if ok {
+		est.Observed = true
+		speed = max(speed, minSpeedKmh)
+		spread = min(max(spread, minSpread), maxSpread)
+	} else {
+		speed, spread = fallbackSpeedKmh, fallbackSpread
+	}
+	est.SpeedKmh = math.Round(speed*10) / 10
+	est.RemainingKm = math.Round(est.RemainingKm*100) / 100
+
+	arrival := func(kmh float64) time.Time {
+		t := last.ts.Add(time.Duration(est.RemainingKm / kmh * float64(time.Hour)))
+		if t.Before(now) {
+			t = now
+		}
+		return t.UTC().Truncate(time.Minute)
+	}
+	est.ETA = arrival(speed)
+	est.Range = Range{Earliest: arrival(speed * (1 + spread)), Latest: arrival(speed * (1 - spread))}
+	return est
+}
+
+// recent keeps the samples inside the observation window.
+func recent(samples []sample) []sample {
+	last := samples[len(samples)-1].ts
+	i := max(0, len(samples)-Samples)
+	for i < len(samples)-1 && last.Sub(samples[i].ts) > window {
+		i++
+	}
+	return samples[i:]
+}
+
+// observedSpeed returns the average speed in km/h over the samples and the
+// relative spread of the speeds of their legs.
+func observedSpeed(samples []sample) (speed, spread float64, ok bool) {
+	var km float64
+	var elapsed time.Duration
+	var legs []float64
+	from := samples[0]
+	for _, s := range samples[1:] {
+		dt := s.ts.Sub(from.ts)
+		if dt < minSegment {
+			continue
+		}
+		d := geo.Distance(from.at, s.at)
+		km += d
+		elapsed += dt
+		legs = append(legs, d/dt.Hours())
+		from = s
+	}
+	if elapsed == 0 {
+		return 0, 0, false
+	}
+	speed = km / elapsed.Hours()
+
+	// The coefficient of variation of the leg speeds; with fewer than three
+	// legs there is too little to go on and the widest band applies.
+	if len(legs) < 3 || speed == 0 {
+		return speed, maxSpread, true
+	}
+	var sq float64
+	for _, v := range legs {
+		sq += (v - speed) * (v - speed)
+	}
+	return speed, math.Sqrt(sq/float64(len(legs))) / speed, true
+}
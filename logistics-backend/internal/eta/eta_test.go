@@ -0,0 +1,123 @@
+package eta
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"logistics-backend/internal/geo"
+	"logistics-backend/internal/store"
+)
+
+func TestComputeUsesScanTime(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	event := func(lat float64, received, scanned time.Time) store.ShipmentEvent {
+		lng := 77.0
+		return store.ShipmentEvent{Latitude: &lat, Longitude: &lng, Timestamp: received, ScannedAt: &scanned}
+	}
+	// The second scan was made half an hour after the first but only
+	// uploaded three hours later.
+	events := []store.ShipmentEvent{
+		event(28.0, start, start),
+		event(28.1, start.Add(3*time.Hour), start.Add(30*time.Minute)),
+	}
+	from := geo.Point{Lat: 28.0, Lng: 77.0}
+	to := geo.Point{Lat: 28.1, Lng: 77.0}
+	want := geo.Distance(from, to) * 2
+
+	est := Compute(geo.Point{Lat: 29.0, Lng: 77.0}, events, start)
+	if est == nil || !est.Observed {
+		t.Fatalf("got %+v, want an observed speed", est)
+	}
+	if math.Abs(est.SpeedKmh-want) > 0.1 {
+		t.Fatalf("speed %.1f km/h, want %.1f", est.SpeedKmh, want)
+	}
+}
+
+// located is an event received at ts on the meridian at 77°E.
+func located(lat float64, ts time.Time) store.ShipmentEvent {
+	lng := 77.0
+	return store.ShipmentEvent{Latitude: &lat, Longitude: &lng, Timestamp: ts}
+}
+
+func TestComputeFallback(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	dest := geo.Point{Lat: 28.9, Lng: 77.0}
+	if est := Compute(dest, []store.ShipmentEvent{{Timestamp: start}}, start); est != nil {
+		t.Fatalf("estimated %+v without a position", est)
+	}
+
+	for name, events := range map[string][]store.ShipmentEvent{
+		"one position": {located(28.0, start)},
+		// Legs under a minute say nothing about speed.
+		"one short leg": {located(27.99, start.Add(-30*time.Second)), located(28.0, start)},
+	} {
+		est := Compute(dest, events, start)
+		if est == nil || est.Observed || est.SpeedKmh != 30 {
+			t.Fatalf("%s: %+v, want the 30 km/h fallback", name, est)
+		}
+		hours := func(kmh float64) time.Time {
+			return start.Add(time.Duration(est.RemainingKm / kmh * float64(time.Hour))).Truncate(time.Minute)
+		}
+		if !est.ETA.Equal(hours(30)) || !est.Range.Earliest.Equal(hours(45)) || !est.Range.Latest.Equal(hours(15)) {
+			t.Fatalf("%s: ETA %v in %+v for %.2f km", name, est.ETA, est.Range, est.RemainingKm)
+		}
+	}
+
+	// Arrivals already due are moved up to now.
+	later := start.Add(24 * time.Hour)
+	if est := Compute(dest, []store.ShipmentEvent{located(28.0, start)}, later); !est.ETA.Equal(later) || !est.Range.Latest.Equal(later) {
+		t.Fatalf("overdue ETA %v in %+v, want %v", est.ETA, est.Range, later)
+	}
+}
+
+func TestComputeWindow(t *testing.T) {
+	last := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	dest := geo.Point{Lat: 29.0, Lng: 77.0}
+	// A long first leg, then a crawl.
+	far, near, here := geo.Point{Lat: 27.0, Lng: 77.0}, geo.Point{Lat: 28.0, Lng: 77.0}, geo.Point{Lat: 28.01, Lng: 77.0}
+	events := func(first time.Duration) []store.ShipmentEvent {
+		return []store.ShipmentEvent{
+			located(far.Lat, last.Add(-first)),
+			located(near.Lat, last.Add(-time.Hour)),
+			located(here.Lat, last),
+		}
+	}
+
+	// The first sample is kept at exactly two hours.
+	want := (geo.Distance(far, near) + geo.Distance(near, here)) / 2
+	if est := Compute(dest, events(window), last); math.Abs(est.SpeedKmh-want) > 0.1 {
+		t.Fatalf("speed %.1f km/h at the window's edge, want %.1f", est.SpeedKmh, want)
+	}
+	// A second earlier it is dropped, and the crawl is floored.
+	if est := Compute(dest, events(window+time.Second), last); !est.Observed || est.SpeedKmh != minSpeedKmh {
+		t.Fatalf("speed %.1f km/h past the window, want %v", est.SpeedKmh, minSpeedKmh)
+	}
+}
+
+func TestComputeSamples(t *testing.T) {
+	last := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	dest := geo.Point{Lat: 29.0, Lng: 77.0}
+	// n positions a minute apart, 0.01° further each, after a first one
+	// a degree back.
+	events := func(n int) []store.ShipmentEvent {
+		var evs []store.ShipmentEvent
+		for i := range n {
+			lat := 28.0 + float64(i)*0.01
+			if i == 0 {
+				lat = 27.0
+			}
+			evs = append(evs, located(lat, last.Add(time.Duration(i-n+1)*time.Minute)))
+		}
+		return evs
+	}
+	step := geo.Distance(geo.Point{Lat: 28.0, Lng: 77.0}, geo.Point{Lat: 28.01, Lng: 77.0}) * 60
+
+	// With Samples positions the first is in; one more pushes it out.
+	if est := Compute(dest, events(Samples), last); est.SpeedKmh < 2*step {
+		t.Fatalf("speed %.1f km/h over %d samples, want the first leg counted", est.SpeedKmh, Samples)
+	}
+	if est := Compute(dest, events(Samples+1), last); math.Abs(est.SpeedKmh-step) > 0.5 {
+		t.Fatalf("speed %.1f km/h over %d samples, want %.1f", est.SpeedKmh, Samples+1, step)
+	}
+}
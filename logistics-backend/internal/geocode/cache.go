@@ -0,0 +1,77 @@
+package geocode
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+
+	"logistics-backend/internal/geo"
+)
+
+// Cache remembers the answers of another Geocoder for the most recently
+// used addresses. Addresses that differ only in case, spacing or
+// punctuation share an entry. ErrNoMatch is remembered too, so an unknown
+// address is not looked up again on every edit; other errors are not.
+type Cache struct {
+	next Geocoder
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order holds the entries, most recently used first.
+	order *list.List
+}
+
+type cacheEntry struct {
+	key   string
+	point geo.Point
+	err   error
+}
+
+// NewCache wraps next with a cache of at most size addresses.
+func NewCache(next Geocoder, size int) *Cache {
+	return &Cache{
+		next:    next,
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (c *Cache) Geocode(ctx context.Context, address string) (geo.Point, error) {
+	key := normalize(address)
+	if key == "" {
+		return geo.Point{}, ErrNoMatch
+	}
+
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		e := el.Value.(*cacheEntry)
+		c.mu.Unlock()
+		return e.point, e.err
+	}
+	c.mu.Unlock()
+
+	// Concurrent misses for one address may both ask next; the later answer
+	// replaces the earlier.
+	point, err := c.next.Geocode(ctx, address)
+	if err != nil && !errors.Is(err, ErrNoMatch) {
+		return point, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		el.Value = &cacheEntry{key, point, err}
+		return point, err
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key, point, err})
+	for c.order.Len() > c.size {
+		oldest := c.order.Remove(c.order.Back()).(*cacheEntry)
+		delete(c.entries, oldest.key)
+	}
+	return point, err
+}
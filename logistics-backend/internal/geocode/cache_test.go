@@ -0,0 +1,110 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"logistics-backend/internal/geo"
+)
+
+// countingGeocoder places the addresses it knows and counts the lookups.
+type countingGeocoder struct {
+	known map[string]geo.Point
+	fail  error
+	calls int
+}
+
+func (g *countingGeocoder) Geocode(_ context.Context, address string) (geo.Point, error) {
+	g.calls++
+	if g.fail != nil {
+		return geo.Point{}, g.fail
+	}
+	if p, ok := g.known[address]; ok {
+		return p, nil
+	}
+	return geo.Point{}, ErrNoMatch
+}
+
+func TestCacheEviction(t *testing.T) {
+	next := &countingGeocoder{known: map[string]geo.Point{
+		"a": {Lat: 1}, "b": {Lat: 2}, "c": {Lat: 3},
+	}}
+	c := NewCache(next, 2)
+	ctx := context.Background()
+	lookup := func(address string, calls int) {
+		t.Helper()
+		p, err := c.Geocode(ctx, address)
+		if err != nil || p != next.known[address] {
+			t.Fatalf("Geocode(%q) = %v, %v", address, p, err)
+		}
+		if next.calls != calls {
+			t.Fatalf("after %q: %d lookups, want %d", address, next.calls, calls)
+		}
+	}
+
+	lookup("a", 1)
+	lookup("b", 2)
+	lookup("a", 2)
+	// c evicts b, the least recently used.
+	lookup("c", 3)
+	lookup("a", 3)
+	lookup("c", 3)
+	lookup("b", 4)
+	// and b evicted a.
+	lookup("c", 4)
+	lookup("a", 5)
+}
+
+func TestCacheKeys(t *testing.T) {
+	next := &countingGeocoder{known: map[string]geo.Point{"Navi Mumbai": {Lat: 19.03}}}
+	c := NewCache(next, 10)
+	ctx := context.Background()
+
+	for _, address := range []string{"Navi Mumbai", "navi  mumbai.", " NAVI-MUMBAI "} {
+		if p, err := c.Geocode(ctx, address); err != nil || p.Lat != 19.03 {
+			t.Fatalf("Geocode(%q) = %v, %v", address, p, err)
+		}
+	}
+	if next.calls != 1 {
+		t.Fatalf("%d lookups for one address written three ways", next.calls)
+	}
+
+	// No match is remembered, and an empty address is never looked up.
+	for range 2 {
+		if _, err := c.Geocode(ctx, "Nowhere"); !errors.Is(err, ErrNoMatch) {
+			t.Fatalf("Geocode(Nowhere): %v", err)
+		}
+		if _, err := c.Geocode(ctx, " , "); !errors.Is(err, ErrNoMatch) {
+			t.Fatalf("Geocode of punctuation: %v", err)
+		}
+	}
+	if next.calls != 2 {
+		t.Fatalf("%d lookups, want 2", next.calls)
+	}
+
+	// Failed lookups are not.
+	down := errors.New("geocoder unavailable")
+	next.fail = down
+	for range 2 {
+		if _, err := c.Geocode(ctx, "Pune"); !errors.Is(err, down) {
+			t.Fatalf("Geocode(Pune): %v, want %v", err, down)
+		}
+	}
+	if next.calls != 4 {
+		t.Fatalf("%d lookups, want 4", next.calls)
+	}
+}
+
+func TestCacheSizeZero(t *testing.T) {
+	next := &countingGeocoder{known: map[string]geo.Point{"a": {Lat: 1}}}
+	c := NewCache(next, 0)
+	for i := 1; i <= 3; i++ {
+		if p, err := c.Geocode(context.Background(), "a"); err != nil || p.Lat != 1 {
+			t.Fatalf("Geocode(a) = %v, %v", p, err)
+		}
+		if next.calls != i {
+			t.Fatalf("%d lookups after %d calls, want every call passed on", next.calls, i)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package geocode
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"logistics-backend/internal/geo"
+)
+
+// bundledGazetteer covers the head post offices of major Indian cities.
+// Deployments that need village-level accuracy load the full PIN-code
+// directory with LoadGazetteer instead.
+//
+//go:embed gazetteer.csv
+var bundledGazetteer string
+
+// Gazetteer is an offline Geocoder over a table of PIN codes and place
+// names. An address is placed, in order of preference, by an exact PIN
+// code, by a place or district name it contains, or by the first three
+// digits of its PIN code, which identify a sorting district. Of several
+// names the one closest to the end of the address wins, since addresses
+// end with the town and names earlier on tend to be streets or localities.
+type Gazetteer struct {
+	pins map[string]geo.Point
+	// districts holds the centroid of the PIN codes sharing three digits.
+	districts map[string]geo.Point
+	// names are normalized place names, longest first so that "navi mumbai"
+	// wins over the "mumbai" it ends with.
+	names []placeName
+}
+
+type placeName struct {
+	name  string
+	point geo.Point
+}
+
+// gazetteerColumns are the columns a gazetteer CSV must have. Others, such
+// as state, are ignored.
+var gazetteerColumns = []string{"pincode", "place", "district", "latitude", "longitude"}
+
+// LoadGazetteer reads a gazetteer CSV from path, or the bundled one when
+// path is empty.
+func LoadGazetteer(path string) (*Gazetteer, error) {
+	if path == "" {
+		return NewGazetteer(strings.NewReader(bundledGazetteer))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	g, err := NewGazetteer(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return g, nil
+}
+
+// NewGazetteer reads a CSV whose header names at least the pincode, place,
+// district, latitude and longitude columns. A PIN code may appear on
+// several rows to give a place alternative names; its first row places it.
+func NewGazetteer(r io.Reader) (*Gazetteer, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading gazetteer header: %w", err)
+	}
+	col := make(map[string]int)
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range gazetteerColumns {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("gazetteer has no %s column", name)
+		}
+	}
+
+	g := &Gazetteer{pins: make(map[string]geo.Point), districts: make(map[string]geo.Point)}
+	places := make(map[string]geo.Point)
+	districtNames := make(map[string]geo.Point)
+	type sum struct {
+		lat, lng float64
+		n        int
+	}
+	sums := make(map[string]*sum)
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		pin := strings.TrimSpace(rec[col["pincode"]])
+		if !isPIN(pin) {
+			return nil, fmt.Errorf("line %d: %q is not a PIN code", line, pin)
+		}
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(rec[col["latitude"]]), 64)
+		lng, lngErr := strconv.ParseFloat(strings.TrimSpace(rec[col["longitude"]]), 64)
+		if latErr != nil || lngErr != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			return nil, fmt.Errorf("line %d: invalid coordinates", line)
+		}
+		p := geo.Point{Lat: lat, Lng: lng}
+
+		if _, ok := g.pins[pin]; !ok {
+			g.pins[pin] = p
+			s := sums[pin[:3]]
+			if s == nil {
+				s = &sum{}
+				sums[pin[:3]] = s
+			}
+			s.lat, s.lng, s.n = s.lat+lat, s.lng+lng, s.n+1
+		}
+		if name := normalize(rec[col["place"]]); name != "" {
+			if _, ok := places[name]; !ok {
+				places[name] = p
+			}
+		}
+		if name := normalize(rec[col["district"]]); name != "" {
+			if _, ok := districtNames[name]; !ok {
+				districtNames[name] = p
+			}
+		}
+	}
+
+	for prefix, s := range sums {
+		g.districts[prefix] = geo.Point{Lat: s.lat / float64(s.n), Lng: s.lng / float64(s.n)}
+	}
+	// A place shadows a district of the same name.
+	for name, p := range districtNames {
+		if _, ok := places[name]; !ok {
+			places[name] = p
+		}
+	}
+	for name, p := range places {
+		g.names = append(g.names, placeName{name, p})
+	}
+	slices.SortFunc(g.names, func(a, b placeName) int {
+		return cmp.Or(cmp.Compare(len(b.name), len(a.name)), strings.Compare(a.name, b.name))
+	})
+	return g, nil
+}
+
+// Geocode places address or returns ErrNoMatch.
+func (g *Gazetteer) Geocode(ctx context.Context, address string) (geo.Point, error) {
+	addr := normalize(address)
+	pins := findPINs(addr)
+	// The PIN code usually ends the address; earlier numbers may be house
+	// or plot numbers that happen to have six digits.
+	for i := len(pins) - 1; i >= 0; i-- {
+		if p, ok := g.pins[pins[i]]; ok {
+			return p, nil
+		}
+	}
+	best, bestEnd := -1, -1
+	for i, n := range g.names {
+		if at := strings.LastIndex(addr, n.name); at >= 0 && at+len(n.name) > bestEnd {
+			best, bestEnd = i, at+len(n.name)
+		}
+	}
+	if best >= 0 {
+		return g.names[best].point, nil
+	}
+	for i := len(pins) - 1; i >= 0; i-- {
+		if p, ok := g.districts[pins[i][:3]]; ok {
+			return p, nil
+		}
+	}
+	return geo.Point{}, ErrNoMatch
+}
+
+// findPINs returns the PIN codes in a normalized address, including those
+// written with a space after the third digit, as in "110 001".
+func findPINs(addr string) []string {
+	words := strings.Fields(addr)
+	var pins []string
+	for i, w := range words {
+		if isPIN(w) {
+			pins = append(pins, w)
+		} else if len(w) == 3 && i+1 < len(words) && isPIN(w+words[i+1]) {
+			pins = append(pins, w+words[i+1])
+		}
+	}
+	return pins
+}
+
+// isPIN reports whether s is a six-digit Indian postal index number, which
+// never starts with 0.
+func isPIN(s string) bool {
+	if len(s) != 6 || s[0] == '0' {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"logistics-backend/internal/geo"
+)
+
+const testGazetteer = `pincode,place,district,state,latitude,longitude
+400001,Mumbai,Mumbai,Maharashtra,18.94,72.83
+400703,Navi Mumbai,Thane,Maharashtra,19.03,73.02
+411001,Pune,Pune,Maharashtra,18.52,73.85
+110001,New Delhi,New Delhi,Delhi,28.63,77.22
+110002,Daryaganj,Central Delhi,Delhi,28.64,77.24
+`
+
+func TestGazetteerGeocode(t *testing.T) {
+	g, err := NewGazetteer(strings.NewReader(testGazetteer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mumbai     = geo.Point{Lat: 18.94, Lng: 72.83}
+		naviMumbai = geo.Point{Lat: 19.03, Lng: 73.02}
+		pune       = geo.Point{Lat: 18.52, Lng: 73.85}
+		newDelhi   = geo.Point{Lat: 28.63, Lng: 77.22}
+		daryaganj  = geo.Point{Lat: 28.64, Lng: 77.24}
+		// The centroid of the PIN codes starting 400.
+		district400 = geo.Point{Lat: (18.94 + 19.03) / 2, Lng: (72.83 + 73.02) / 2}
+	)
+	tests := []struct {
+		address string
+		want    geo.Point
+	}{
+		// An exact PIN code comes first, even over a name.
+		{"Sector 17, Vashi 400703", naviMumbai},
+		{"12 Pune Road, Mumbai 411001", pune},
+		{"New Delhi 110 001", newDelhi},
+		// Of several PIN codes the last one is the postal code.
+		{"Plot 110001, Colaba, 400001", mumbai},
+		// Then names, the longest where they end together.
+		{"Sector 17, Navi Mumbai", naviMumbai},
+		{"Navi Mumbai Road, Mumbai", mumbai},
+		{"MUMBAI ROAD, PUNE.", pune},
+		{"Mumbai, Pune, Mumbai", mumbai},
+		// District names count as names.
+		{"Ghodbunder Road, Thane", naviMumbai},
+		{"Chandni Chowk, Central Delhi", daryaganj},
+		// A name beats a PIN code the gazetteer lacks.
+		{"Daryaganj 400099", daryaganj},
+		// Last, the sorting district of an unknown PIN code.
+		{"Unknown Lane 400099", district400},
+		{"Unknown Lane 400 099", district400},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		got, err := g.Geocode(ctx, tt.address)
+		if err != nil {
+			t.Errorf("Geocode(%q): %v", tt.address, err)
+			continue
+		}
+		if math.Abs(got.Lat-tt.want.Lat) > 1e-9 || math.Abs(got.Lng-tt.want.Lng) > 1e-9 {
+			t.Errorf("Geocode(%q) = %v, want %v", tt.address, got, tt.want)
+		}
+	}
+
+	for _, address := range []string{"", "Nowhere", "Mumbaikar Street", "Lane 500001", "Lane 012345"} {
+		if _, err := g.Geocode(ctx, address); !errors.Is(err, ErrNoMatch) {
+			t.Errorf("Geocode(%q): %v, want ErrNoMatch", address, err)
+		}
+	}
+}
+
+func TestNewGazetteerRejects(t *testing.T) {
+	for name, csv := range map[string]string{
+		"missing column": "pincode,place,latitude,longitude\n400001,Mumbai,18.94,72.83\n",
+		"bad PIN":        "pincode,place,district,latitude,longitude\n040001,Mumbai,Mumbai,18.94,72.83\n",
+		"bad latitude":   "pincode,place,district,latitude,longitude\n400001,Mumbai,Mumbai,98.94,72.83\n",
+	} {
+		if _, err := NewGazetteer(strings.NewReader(csv)); err == nil {
+			t.Errorf("%s: gazetteer accepted", name)
+		}
+	}
+	if _, err := LoadGazetteer(""); err != nil {
+		t.Fatalf("bundled gazetteer: %v", err)
+	}
+}
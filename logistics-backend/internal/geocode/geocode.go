@@ -0,0 +1,34 @@
+// Package geocode turns destination addresses into coordinates.
+package geocode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+
+	"logistics-backend/internal/geo"
+)
+
+// ErrNoMatch means the address could not be placed. Shipments with such an
+// address need their pin placed by hand.
+var ErrNoMatch = errors.New("geocode: address not found")
+
+// Geocoder places a free-text address. It returns ErrNoMatch when the
+// address is not recognised and other errors when the lookup itself failed.
+type Geocoder interface {
+	Geocode(ctx context.Context, address string) (geo.Point, error)
+}
+
+// normalize lowercases an address and reduces it to words separated by
+// single spaces, with a space at either end so that whole words can be
+// found with strings.Contains.
+func normalize(address string) string {
+	words := strings.FieldsFunc(strings.ToLower(address), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	return " " + strings.Join(words, " ") + " "
+}
@@ -0,0 +1,137 @@
+// Package health serves the liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is stamped at build time with
+// -ldflags "-X logistics-backend/internal/health.Version=v1.2.3".
+var Version = "dev"
+
+// SchemaVersioner reports the applied and the newest known schema version.
+type SchemaVersioner interface {
+	Version(ctx context.Context) (int64, error)
+	Latest() int64
+}
+
+type Handler struct {
+	// DB is pinged by the readiness probe. It is nil for the in-memory store.
+	DB     *sql.DB
+	Schema SchemaVersioner
+
+	started time.Time
+	build   BuildInfo
+}
+
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	Revision  string `json:"revision,omitempty"`
+	BuiltAt   string `json:"vcs_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+func NewHandler(db *sql.DB, schema SchemaVersioner) *Handler {
+	return &Handler{DB: db, Schema: schema, started: time.Now(), build: readBuildInfo()}
+}
+
+func readBuildInfo() BuildInfo {
+	b := BuildInfo{Version: Version}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	b.GoVersion = info.GoVersion
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			b.Revision = s.Value
+		case "vcs.time":
+			b.BuiltAt = s.Value
+		case "vcs.modified":
+			b.Modified = s.Value == "true"
+		}
+	}
+	return b
+}
+
+// Liveness answers 200 as long as the process can serve requests.
+func (h *Handler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"uptime": time.Since(h.started).Round(time.Second).String(),
+		"build":  h.build,
+	})
+}
+
+// Readiness checks the database and schema and answers 503 when either is
+// unusable.
+func (h *Handler) Readiness(c *gin.Context) {
+	ready := true
+	checks := gin.H{}
+
+	if h.DB == nil {
+		checks["database"] = gin.H{"status": "ok", "backend": "memory"}
+	} else {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+
+		db := gin.H{}
+		start := time.Now()
+		err := h.DB.PingContext(ctx)
+		db["latency_ms"] = float64(time.Since(start).Microseconds()) / 1000
+		if err != nil {
+			ready = false
+			db["status"] = "down"
+			db["error"] = err.Error()
+		} else {
+			db["status"] = "ok"
+		}
+
+		stats := h.DB.Stats()
+		db["pool"] = gin.H{
+			"max_open":            stats.MaxOpenConnections,
+			"open":                stats.OpenConnections,
+			"in_use":              stats.InUse,
+			"idle":                stats.Idle,
+			"wait_count":          stats.WaitCount,
+			"wait_duration":       stats.WaitDuration.String(),
+			"max_idle_closed":     stats.MaxIdleClosed,
+			"max_lifetime_closed": stats.MaxLifetimeClosed,
+		}
+		checks["database"] = db
+
+		if h.Schema != nil && err == nil {
+			schema := gin.H{"latest": h.Schema.Latest()}
+			version, err := h.Schema.Version(ctx)
+			if err != nil {
+				ready = false
+				schema["status"] = "down"
+				schema["error"] = err.Error()
+			} else {
+				schema["version"] = version
+				schema["status"] = "ok"
+				if version < h.Schema.Latest() {
+					ready = false
+					schema["status"] = "pending migrations"
+				}
+			}
+			checks["schema"] = schema
+		}
+	}
+
+	status := http.StatusOK
+	body := gin.H{"status": "ready", "checks": checks, "build": h.build}
+	if !ready {
+		status = http.StatusServiceUnavailable
+		body["status"] = "not ready"
+	}
+	c.JSON(status, body)
+}
@@ -0,0 +1,244 @@
+// Package label renders printable shipping labels: PDF sheets for office
+// printers and ZPL for Zebra-compatible thermal printers. Every label
+// carries the tracking number as a Code128 barcode and as a QR code.
+package label
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"github.com/go-pdf/fpdf"
+)
+
+// Formats labels can be rendered in.
+const (
+	FormatPDF = "pdf"
+	FormatZPL = "zpl"
+)
+
+var Formats = []string{FormatPDF, FormatZPL}
+
+var ErrUnknownFormat = errors.New("unknown label format")
+
+// ContentType is the media type a format is served as.
+func ContentType(format string) string {
+	if format == FormatZPL {
+		return "application/zpl; charset=utf-8"
+	}
+	return "application/pdf"
+}
+
+// Label is what gets printed for one shipment.
+type Label struct {
+	TrackingNumber string
+	// Origin is the name of the origin hub and OriginCode its short code,
+	// or, for a shipment collected from an address, the address alone.
+	Origin      string
+	OriginCode  string
+	Destination string
+	Pieces      int
+}
+
+// from names the origin with its hub code, when it has one.
+func (l Label) from() string {
+	if l.OriginCode == "" {
+		return oneLine(l.Origin)
+	}
+	return l.Origin + " (" + l.OriginCode + ")"
+}
+
+// Write renders labels in format to w.
+func Write(w io.Writer, format string, labels []Label) error {
+	switch format {
+	case FormatPDF:
+		return PDF(w, labels)
+	case FormatZPL:
+		return ZPL(w, labels)
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+// Label geometry of the PDF, in millimetres. Four A6 labels fill an A4
+// sheet, so they can be cut apart or printed on quarter-sheet label stock.
+const (
+	sheetW, sheetH = 210.0, 297.0
+	labelW, labelH = sheetW / 2, sheetH / 2
+	margin         = 6.0
+	perSheet       = 4
+)
+
+// PDF lays labels out four to an A4 page.
+func PDF(w io.Writer, labels []Label) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle("Shipping labels", true)
+	// The core fonts only cover Windows-1252; other characters are replaced.
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for i, l := range labels {
+		if i%perSheet == 0 {
+			pdf.AddPage()
+		}
+		x := float64(i%2) * labelW
+		y := float64(i%perSheet/2) * labelH
+		if err := drawLabel(pdf, tr, x, y, l); err != nil {
+			return fmt.Errorf("label %s: %w", l.TrackingNumber, err)
+		}
+	}
+	if len(labels) == 0 {
+		pdf.AddPage()
+	}
+	return pdf.Output(w)
+}
+
+func drawLabel(pdf *fpdf.Fpdf, tr func(string) string, x, y float64, l Label) error {
+	left, width := x+margin, labelW-2*margin
+
+	// Cut lines around the label.
+	pdf.SetDrawColor(160, 160, 160)
+	pdf.SetLineWidth(0.2)
+	pdf.SetDashPattern([]float64{2, 2}, 0)
+	pdf.Rect(x+1, y+1, labelW-2, labelH-2, "D")
+	pdf.SetDashPattern(nil, 0)
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetLineWidth(0.4)
+
+	cy := y + margin + 3
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.Text(left, cy, "FROM")
+	pdf.SetFont("Helvetica", "B", 12)
+	// The origin gets a single line; a long pickup address is cut short.
+	from := pdf.SplitText(tr(l.from()), width)
+	if len(from) > 1 {
+		from[0] = strings.TrimSpace(from[0]) + "..."
+	}
+	if len(from) > 0 {
+		pdf.Text(left, cy+6, from[0])
+	}
+
+	cy += 14
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.Text(left, cy, "TO")
+	pdf.SetFont("Helvetica", "B", 12)
+	lines := pdf.SplitText(tr(oneLine(l.Destination)), width)
+	if len(lines) > 4 {
+		lines = append(lines[:3], strings.TrimSpace(lines[3])+"...")
+	}
+	for i, line := range lines {
+		pdf.Text(left, cy+6+float64(i)*5.5, line)
+	}
+
+	cy = y + 52
+	pdf.Line(left, cy, left+width, cy)
+	bars, err := code128.Encode(l.TrackingNumber)
+	if err != nil {
+		return err
+	}
+	drawBars(pdf, bars, left+5, cy+5, width-10, 24)
+	pdf.SetFont("Courier", "B", 12)
+	pdf.SetXY(left, cy+30)
+	pdf.CellFormat(width, 5, l.TrackingNumber, "", 0, "C", false, 0, "")
+
+	cy += 40
+	pdf.Line(left, cy, left+width, cy)
+	code, err := qr.Encode(l.TrackingNumber, qr.M, qr.Auto)
+	if err != nil {
+		return err
+	}
+	drawModules(pdf, code, left, cy+5, 40)
+
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.Text(left+52, cy+10, "PIECES")
+	pdf.SetFont("Helvetica", "B", 40)
+	pdf.Text(left+52, cy+28, fmt.Sprint(l.Pieces))
+	if pdf.Err() {
+		return pdf.Error()
+	}
+	return nil
+}
+
+// dark reports whether a barcode module is printed.
+func dark(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r+g+b < 3*0x8000
+}
+
+// drawBars draws a one-dimensional barcode into a w by h box, merging
+// adjacent dark modules into a single bar.
+func drawBars(pdf *fpdf.Fpdf, code barcode.Barcode, x, y, w, h float64) {
+	n := code.Bounds().Dx()
+	module := w / float64(n)
+	for i := 0; i < n; {
+		if !dark(code.At(i, 0)) {
+			i++
+			continue
+		}
+		start := i
+		for i < n && dark(code.At(i, 0)) {
+			i++
+		}
+		pdf.Rect(x+float64(start)*module, y, float64(i-start)*module, h, "F")
+	}
+}
+
+// drawModules draws a two-dimensional barcode into a size by size square.
+func drawModules(pdf *fpdf.Fpdf, code barcode.Barcode, x, y, size float64) {
+	n := code.Bounds().Dx()
+	module := size / float64(n)
+	for row := 0; row < n; row++ {
+		for col := 0; col < n; {
+			if !dark(code.At(col, row)) {
+				col++
+				continue
+			}
+			start := col
+			for col < n && dark(code.At(col, row)) {
+				col++
+			}
+			pdf.Rect(x+float64(start)*module, y+float64(row)*module, float64(col-start)*module, module, "F")
+		}
+	}
+}
+
+// ZPL writes one 4x6 inch label per shipment for 203 dpi printers, which
+// draw both barcodes themselves.
+func ZPL(w io.Writer, labels []Label) error {
+	for _, l := range labels {
+		_, err := fmt.Fprintf(w, `^XA
+^CI28
+^PW812
+^LL1218
+^FO40,40^A0N,26,26^FDFROM^FS
+^FO40,72^A0N,44,44^FB732,1,0,L^FH^FD%s^FS
+^FO40,150^A0N,26,26^FDTO^FS
+^FO40,182^A0N,44,44^FB732,4,6,L^FH^FD%s^FS
+^FO40,400^GB732,3,3^FS
+^FO40,440^BY3^BCN,220,Y,N,N,A^FD%s^FS
+^FO40,740^GB732,3,3^FS
+^FO40,780^BQN,2,10^FDMA,%s^FS
+^FO480,800^A0N,26,26^FDPIECES^FS
+^FO480,840^A0N,180,180^FD%d^FS
+^XZ
+`, zplField(l.from()), zplField(oneLine(l.Destination)), l.TrackingNumber, l.TrackingNumber, l.Pieces)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zplField escapes the characters ZPL treats as commands in a field
+// printed with ^FH, whose escape character is the underscore.
+var zplField = strings.NewReplacer("_", "_5F", "^", "_5E", "~", "_7E").Replace
+
+// oneLine joins the lines of a multi-line address with commas.
+func oneLine(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == '\r' }), ", ")
+}
@@ -0,0 +1,140 @@
+package label
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/boombuler/barcode/code128"
+)
+
+// contents inflates the content streams of a PDF written by PDF.
+func contents(t *testing.T, pdf []byte) string {
+	t.Helper()
+	var out strings.Builder
+	rest := pdf
+	for {
+		var stream []byte
+		var ok bool
+		if _, rest, ok = bytes.Cut(rest, []byte(">>\nstream\n")); !ok {
+			break
+		}
+		if stream, rest, ok = bytes.Cut(rest, []byte("\nendstream")); !ok {
+			break
+		}
+		r, err := zlib.NewReader(bytes.NewReader(stream))
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.Write(data)
+	}
+	return out.String()
+}
+
+var filledRect = regexp.MustCompile(`(-?[\d.]+) (-?[\d.]+) (-?[\d.]+) (-?[\d.]+) re f`)
+
+func TestPDF(t *testing.T) {
+	l := Label{TrackingNumber: "LGDEL000000018", Origin: "Delhi Hub", OriginCode: "DEL", Destination: "12 MG Road\nBengaluru", Pieces: 2}
+	var buf bytes.Buffer
+	if err := PDF(&buf, []Label{l}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) || !bytes.HasSuffix(bytes.TrimSpace(buf.Bytes()), []byte("%%EOF")) {
+		t.Fatalf("PDF starts %q", buf.Bytes()[:min(buf.Len(), 16)])
+	}
+	content := contents(t, buf.Bytes())
+	if !strings.Contains(content, "("+l.TrackingNumber+")Tj") {
+		t.Errorf("tracking number is not printed under the barcode")
+	}
+
+	// Read the bars back, one module at a time, and compare them with the
+	// Code128 encoding of the tracking number.
+	code, err := code128.Encode(l.TrackingNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := code.Bounds().Dx()
+	const k = 72 / 25.4
+	module := (labelW - 2*margin - 10) / float64(n) * k
+	left := (margin + 5) * k
+	drawn := make([]bool, n)
+	for _, m := range filledRect.FindAllStringSubmatch(content, -1) {
+		x, _ := strconv.ParseFloat(m[1], 64)
+		w, _ := strconv.ParseFloat(m[3], 64)
+		h, _ := strconv.ParseFloat(m[4], 64)
+		if math.Abs(h+24*k) > 0.01 {
+			continue // not a bar
+		}
+		start := int(math.Round((x - left) / module))
+		for i := start; i < start+int(math.Round(w/module)); i++ {
+			if i < 0 || i >= n {
+				t.Fatalf("bar at %.2f runs outside the barcode", x)
+			}
+			drawn[i] = true
+		}
+	}
+	for i := range n {
+		if want := dark(code.At(i, 0)); drawn[i] != want {
+			t.Fatalf("module %d of the barcode drawn %t, want %t", i, drawn[i], want)
+		}
+	}
+}
+
+func TestPDFPages(t *testing.T) {
+	for _, tt := range []struct{ labels, pages int }{{0, 1}, {1, 1}, {4, 1}, {5, 2}, {9, 3}} {
+		labels := make([]Label, tt.labels)
+		for i := range labels {
+			labels[i] = Label{TrackingNumber: "LGDEL0000000" + strconv.Itoa(10+i), Origin: "Delhi Hub", Pieces: 1}
+		}
+		var buf bytes.Buffer
+		if err := PDF(&buf, labels); err != nil {
+			t.Fatal(err)
+		}
+		if got := bytes.Count(buf.Bytes(), []byte("/Type /Page\n")); got != tt.pages {
+			t.Errorf("%d labels on %d pages, want %d", tt.labels, got, tt.pages)
+		}
+	}
+}
+
+func TestPDFRejectsUnencodable(t *testing.T) {
+	if err := PDF(io.Discard, []Label{{TrackingNumber: "LG€1"}}); err == nil {
+		t.Fatal("PDF of a tracking number Code128 cannot encode succeeded")
+	}
+}
+
+func TestZPL(t *testing.T) {
+	labels := []Label{
+		{TrackingNumber: "LGDEL000000018", Origin: "Delhi Hub", OriginCode: "DEL", Destination: "Flat ^1_A~\n12 MG Road", Pieces: 3},
+		{TrackingNumber: "LGBOM000000427", Origin: "4 Marine Drive\nMumbai", Destination: "Pune", Pieces: 1},
+	}
+	var buf bytes.Buffer
+	if err := ZPL(&buf, labels); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if got := strings.Count(out, "^XA"); got != 2 {
+		t.Fatalf("%d labels, want 2", got)
+	}
+	first, second, _ := strings.Cut(out, "^XZ")
+	for i, part := range []string{first, second} {
+		tn := labels[i].TrackingNumber
+		if !strings.Contains(part, "^BCN,220,Y,N,N,A^FD"+tn+"^FS") || !strings.Contains(part, "^BQN,2,10^FDMA,"+tn+"^FS") {
+			t.Errorf("label %d does not encode %s:\n%s", i, tn, part)
+		}
+	}
+	if !strings.Contains(first, "^FDDelhi Hub (DEL)^FS") || !strings.Contains(first, "^FDFlat _5E1_5FA_7E, 12 MG Road^FS") {
+		t.Errorf("first label addresses:\n%s", first)
+	}
+	if !strings.Contains(second, "^FD4 Marine Drive, Mumbai^FS") {
+		t.Errorf("second label origin:\n%s", second)
+	}
+}
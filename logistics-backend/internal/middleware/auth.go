@@ -5,13 +5,11 @@ import (
 	"net/http"
 	"strings"
 
-	"logistics-backend/internal/config"
-
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware(requiredRoles ...string) gin.HandlerFunc {
+func AuthMiddleware(jwtSecret string, requiredRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -34,7 +32,7 @@ func AuthMiddleware(requiredRoles ...string) gin.HandlerFunc {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method")
 			}
-			return []byte(config.AppConfig.JWTSecret), nil
+			return []byte(jwtSecret), nil
 		})
 
 		if err != nil || !token.Valid {
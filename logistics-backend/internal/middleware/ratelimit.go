@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// idleClientTTL is how long a client's limiter is kept after its last
+// request.
+const idleClientTTL = 10 * time.Minute
+
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimit allows every client IP perMinute requests per minute, with
+// bursts of up to burst requests. Clients over the limit get a 429 with a
+// Retry-After header.
+func RateLimit(perMinute, burst int) gin.HandlerFunc {
+	var (
+		mu        sync.Mutex
+		clients   = make(map[string]*client)
+		lastSweep = time.Now()
+	)
+	limit := rate.Limit(float64(perMinute) / 60)
+
+	return func(c *gin.Context) {
+		now := time.Now()
+		ip := c.ClientIP()
+
+		mu.Lock()
+		if now.Sub(lastSweep) > idleClientTTL {
+			for key, cl := range clients {
+				if now.Sub(cl.lastSeen) > idleClientTTL {
+					delete(clients, key)
+				}
+			}
+			lastSweep = now
+		}
+		cl, ok := clients[ip]
+		if !ok {
+			cl = &client{limiter: rate.NewLimiter(limit, burst)}
+			clients[ip] = cl
+		}
+		cl.lastSeen = now
+		reservation := cl.limiter.ReserveN(now, 1)
+		delay := reservation.DelayFrom(now)
+		if delay > 0 {
+			// Hand the token back so rejected requests do not push the
+			// client's next allowed request further out.
+			reservation.CancelAt(now)
+		}
+		mu.Unlock()
+
+		if delay > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/track", RateLimit(1, 3), func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func(ip string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/track", nil)
+		req.RemoteAddr = ip + ":40000"
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// The burst goes through, then one request a minute.
+	for i := range 3 {
+		if w := get("192.0.2.1"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i+1, w.Code)
+		}
+	}
+	for range 2 {
+		w := get("192.0.2.1")
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("over the burst: status %d, want 429", w.Code)
+		}
+		// Rejected requests do not push the next allowed one further out.
+		if retry, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retry < 1 || retry > 60 {
+			t.Fatalf("Retry-After %q, want 1 to 60 seconds", w.Header().Get("Retry-After"))
+		}
+	}
+
+	// Other clients have limits of their own.
+	if w := get("192.0.2.2"); w.Code != http.StatusOK {
+		t.Fatalf("another client: status %d", w.Code)
+	}
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS warehouses (
+    id        BIGINT AUTO_INCREMENT PRIMARY KEY,
+    name      VARCHAR(255)  NOT NULL,
+    latitude  DECIMAL(10,7) NOT NULL,
+    longitude DECIMAL(10,7) NOT NULL
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS shipments (
+    id                    BIGINT AUTO_INCREMENT PRIMARY KEY,
+    tracking_number       VARCHAR(64)   NOT NULL,
+    origin_warehouse_id   BIGINT        NOT NULL,
+    destination_address   VARCHAR(512)  NOT NULL,
+    destination_latitude  DECIMAL(10,7) NULL,
+    destination_longitude DECIMAL(10,7) NULL,
+    customer_id           BIGINT        NOT NULL,
+    driver_id             BIGINT        NULL,
+    status                VARCHAR(32)   NOT NULL DEFAULT 'pending',
+    created_at            TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at            TIMESTAMP     NULL DEFAULT NULL ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_shipments_tracking_number (tracking_number),
+    KEY idx_shipments_customer (customer_id),
+    KEY idx_shipments_driver (driver_id),
+    KEY idx_shipments_status (status),
+    CONSTRAINT fk_shipments_warehouse FOREIGN KEY (origin_warehouse_id) REFERENCES warehouses (id),
+    CONSTRAINT fk_shipments_customer FOREIGN KEY (customer_id) REFERENCES users (id),
+    CONSTRAINT fk_shipments_driver FOREIGN KEY (driver_id) REFERENCES users (id)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS shipment_events (
+    id          BIGINT AUTO_INCREMENT PRIMARY KEY,
+    shipment_id BIGINT        NOT NULL,
+    status      VARCHAR(32)   NOT NULL,
+    latitude    DECIMAL(10,7) NULL,
+    longitude   DECIMAL(10,7) NULL,
+    timestamp   TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_shipment_events_shipment_time (shipment_id, timestamp),
+    CONSTRAINT fk_shipment_events_shipment FOREIGN KEY (shipment_id) REFERENCES shipments (id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS vehicles (
+    id          BIGINT AUTO_INCREMENT PRIMARY KEY,
+    driver_id   BIGINT        NOT NULL,
+    current_lat DECIMAL(10,7) NOT NULL,
+    current_lng DECIMAL(10,7) NOT NULL,
+    last_update TIMESTAMP     NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_vehicles_driver (driver_id),
+    CONSTRAINT fk_vehicles_driver FOREIGN KEY (driver_id) REFERENCES users (id)
+);
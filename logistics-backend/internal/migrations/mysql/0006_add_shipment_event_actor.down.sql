@@ -0,0 +1,4 @@
+ALTER TABLE shipment_events
+    DROP COLUMN reason,
+    DROP COLUMN actor_role,
+    DROP COLUMN actor_id;
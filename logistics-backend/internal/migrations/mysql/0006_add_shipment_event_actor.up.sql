@@ -0,0 +1,4 @@
+ALTER TABLE shipment_events
+    ADD COLUMN actor_id   BIGINT       NULL AFTER longitude,
+    ADD COLUMN actor_role VARCHAR(32)  NULL AFTER actor_id,
+    ADD COLUMN reason     VARCHAR(255) NULL AFTER actor_role;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS sequences;
+DROP INDEX idx_warehouses_code ON warehouses;
+ALTER TABLE warehouses DROP COLUMN code;
@@ -0,0 +1,6 @@
+ALTER TABLE warehouses ADD COLUMN code VARCHAR(8) NULL AFTER name;
+CREATE UNIQUE INDEX idx_warehouses_code ON warehouses (code);
+CREATE TABLE IF NOT EXISTS sequences (
+    name       VARCHAR(64) PRIMARY KEY,
+    last_value BIGINT      NOT NULL
+);
@@ -0,0 +1,14 @@
+ALTER TABLE shipments ADD COLUMN version BIGINT NOT NULL DEFAULT 1 AFTER status;
+CREATE TABLE IF NOT EXISTS shipment_changes (
+    id          BIGINT AUTO_INCREMENT PRIMARY KEY,
+    shipment_id BIGINT       NOT NULL,
+    version     BIGINT       NOT NULL,
+    field       VARCHAR(64)  NOT NULL,
+    old_value   VARCHAR(512) NULL,
+    new_value   VARCHAR(512) NULL,
+    actor_id    BIGINT       NULL,
+    actor_role  VARCHAR(32)  NULL,
+    changed_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_shipment_changes_shipment (shipment_id, id),
+    CONSTRAINT fk_shipment_changes_shipment FOREIGN KEY (shipment_id) REFERENCES shipments (id) ON DELETE CASCADE
+);
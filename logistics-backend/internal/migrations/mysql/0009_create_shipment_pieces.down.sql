@@ -0,0 +1,5 @@
+ALTER TABLE shipment_events
+    DROP FOREIGN KEY fk_shipment_events_piece,
+    DROP KEY idx_shipment_events_piece,
+    DROP COLUMN piece_id;
+DROP TABLE IF EXISTS shipment_pieces;
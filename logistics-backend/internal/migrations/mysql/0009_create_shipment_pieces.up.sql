@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS shipment_pieces (
+    id             BIGINT AUTO_INCREMENT PRIMARY KEY,
+    shipment_id    BIGINT        NOT NULL,
+    piece_number   INT           NOT NULL,
+    barcode        VARCHAR(80)   NOT NULL,
+    description    VARCHAR(255)  NULL,
+    weight_kg      DECIMAL(10,3) NULL,
+    length_cm      DECIMAL(10,2) NULL,
+    width_cm       DECIMAL(10,2) NULL,
+    height_cm      DECIMAL(10,2) NULL,
+    declared_value DECIMAL(12,2) NULL,
+    status         VARCHAR(32)   NOT NULL DEFAULT 'pending',
+    created_at     TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMP     NULL,
+    UNIQUE KEY idx_shipment_pieces_barcode (barcode),
+    UNIQUE KEY idx_shipment_pieces_number (shipment_id, piece_number),
+    CONSTRAINT fk_shipment_pieces_shipment FOREIGN KEY (shipment_id) REFERENCES shipments (id) ON DELETE CASCADE
+);
+INSERT INTO shipment_pieces (shipment_id, piece_number, barcode, status)
+SELECT id, 1, CONCAT(tracking_number, '-001'), status FROM shipments;
+ALTER TABLE shipment_events
+    ADD COLUMN piece_id BIGINT NULL AFTER shipment_id,
+    ADD KEY idx_shipment_events_piece (piece_id),
+    ADD CONSTRAINT fk_shipment_events_piece FOREIGN KEY (piece_id) REFERENCES shipment_pieces (id) ON DELETE CASCADE;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS delivery_proofs (
+    id             BIGINT AUTO_INCREMENT PRIMARY KEY,
+    shipment_id    BIGINT        NOT NULL,
+    event_id       BIGINT        NOT NULL,
+    recipient_name VARCHAR(255)  NOT NULL,
+    signature_key  VARCHAR(255)  NOT NULL,
+    signature_type VARCHAR(64)   NOT NULL,
+    photo_key      VARCHAR(255)  NULL,
+    photo_type     VARCHAR(64)   NULL,
+    latitude       DECIMAL(10,7) NOT NULL,
+    longitude      DECIMAL(10,7) NOT NULL,
+    created_at     TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY idx_delivery_proofs_shipment (shipment_id),
+    UNIQUE KEY idx_delivery_proofs_event (event_id),
+    CONSTRAINT fk_delivery_proofs_shipment FOREIGN KEY (shipment_id) REFERENCES shipments (id) ON DELETE CASCADE,
+    CONSTRAINT fk_delivery_proofs_event FOREIGN KEY (event_id) REFERENCES shipment_events (id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE shipments ADD COLUMN needs_manual_pin BOOLEAN NOT NULL DEFAULT FALSE AFTER destination_longitude;
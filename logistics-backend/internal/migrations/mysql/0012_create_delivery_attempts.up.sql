@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS delivery_attempts (
+    id              BIGINT AUTO_INCREMENT PRIMARY KEY,
+    shipment_id     BIGINT        NOT NULL,
+    attempt_number  INT           NOT NULL,
+    event_id        BIGINT        NOT NULL,
+    reason          VARCHAR(32)   NOT NULL,
+    notes           VARCHAR(512)  NULL,
+    next_attempt_on TIMESTAMP     NULL,
+    latitude        DECIMAL(10,7) NULL,
+    longitude       DECIMAL(10,7) NULL,
+    actor_id        BIGINT        NULL,
+    actor_role      VARCHAR(32)   NULL,
+    attempted_at    TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY idx_delivery_attempts_number (shipment_id, attempt_number),
+    CONSTRAINT fk_delivery_attempts_shipment FOREIGN KEY (shipment_id) REFERENCES shipments (id) ON DELETE CASCADE,
+    CONSTRAINT fk_delivery_attempts_event FOREIGN KEY (event_id) REFERENCES shipment_events (id) ON DELETE CASCADE
+);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS shipment_returns (
+    id                   BIGINT AUTO_INCREMENT PRIMARY KEY,
+    rma_number           VARCHAR(32)   NOT NULL,
+    original_shipment_id BIGINT        NOT NULL,
+    return_shipment_id   BIGINT        NOT NULL,
+    reason               VARCHAR(32)   NOT NULL,
+    notes                VARCHAR(512)  NULL,
+    pickup_address       VARCHAR(512)  NOT NULL,
+    pickup_latitude      DECIMAL(10,7) NULL,
+    pickup_longitude     DECIMAL(10,7) NULL,
+    requested_by         BIGINT        NULL,
+    requested_role       VARCHAR(32)   NULL,
+    created_at           TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY idx_shipment_returns_rma (rma_number),
+    UNIQUE KEY idx_shipment_returns_original (original_shipment_id),
+    UNIQUE KEY idx_shipment_returns_return (return_shipment_id),
+    CONSTRAINT fk_shipment_returns_original FOREIGN KEY (original_shipment_id) REFERENCES shipments (id) ON DELETE CASCADE,
+    CONSTRAINT fk_shipment_returns_return FOREIGN KEY (return_shipment_id) REFERENCES shipments (id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+ALTER TABLE shipment_events
+    DROP COLUMN scanned_at,
+    DROP COLUMN scan_type;
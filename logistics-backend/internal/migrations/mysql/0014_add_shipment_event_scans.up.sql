@@ -0,0 +1,3 @@
+ALTER TABLE shipment_events
+    ADD COLUMN scan_type  VARCHAR(32) NULL AFTER reason,
+    ADD COLUMN scanned_at TIMESTAMP   NULL AFTER scan_type;
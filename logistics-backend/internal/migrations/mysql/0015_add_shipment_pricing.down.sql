@@ -0,0 +1,5 @@
+ALTER TABLE shipments
+    DROP COLUMN currency,
+    DROP COLUMN price,
+    DROP COLUMN chargeable_weight_kg,
+    DROP COLUMN service_level;
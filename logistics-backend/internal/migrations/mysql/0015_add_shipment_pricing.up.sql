@@ -0,0 +1,5 @@
+ALTER TABLE shipments
+    ADD COLUMN service_level        VARCHAR(32)   NULL AFTER needs_manual_pin,
+    ADD COLUMN chargeable_weight_kg DECIMAL(10,3) NULL AFTER service_level,
+    ADD COLUMN price                DECIMAL(12,2) NULL AFTER chargeable_weight_kg,
+    ADD COLUMN currency             CHAR(3)       NULL AFTER price;
@@ -0,0 +1,4 @@
+ALTER TABLE shipments
+    DROP COLUMN pickup_longitude,
+    DROP COLUMN pickup_latitude,
+    DROP COLUMN pickup_address;
@@ -0,0 +1,4 @@
+ALTER TABLE shipments
+    ADD COLUMN pickup_address   VARCHAR(512)  NULL AFTER origin_warehouse_id,
+    ADD COLUMN pickup_latitude  DECIMAL(10,7) NULL AFTER pickup_address,
+    ADD COLUMN pickup_longitude DECIMAL(10,7) NULL AFTER pickup_latitude;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS warehouses (
+    id        BIGSERIAL PRIMARY KEY,
+    name      VARCHAR(255)     NOT NULL,
+    latitude  DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS shipments (
+    id                    BIGSERIAL PRIMARY KEY,
+    tracking_number       VARCHAR(64)      NOT NULL UNIQUE,
+    origin_warehouse_id   BIGINT           NOT NULL REFERENCES warehouses (id),
+    destination_address   VARCHAR(512)     NOT NULL,
+    destination_latitude  DOUBLE PRECISION NULL,
+    destination_longitude DOUBLE PRECISION NULL,
+    customer_id           BIGINT           NOT NULL REFERENCES users (id),
+    driver_id             BIGINT           NULL REFERENCES users (id),
+    status                VARCHAR(32)      NOT NULL DEFAULT 'pending',
+    created_at            TIMESTAMPTZ      NOT NULL DEFAULT NOW(),
+    updated_at            TIMESTAMPTZ      NULL
+);
+CREATE INDEX IF NOT EXISTS idx_shipments_customer ON shipments (customer_id);
+CREATE INDEX IF NOT EXISTS idx_shipments_driver ON shipments (driver_id);
+CREATE INDEX IF NOT EXISTS idx_shipments_status ON shipments (status);
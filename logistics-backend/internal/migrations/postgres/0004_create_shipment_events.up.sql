@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS shipment_events (
+    id          BIGSERIAL PRIMARY KEY,
+    shipment_id BIGINT           NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+    status      VARCHAR(32)      NOT NULL,
+    latitude    DOUBLE PRECISION NULL,
+    longitude   DOUBLE PRECISION NULL,
+    timestamp   TIMESTAMPTZ      NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_shipment_events_shipment_time ON shipment_events (shipment_id, timestamp);
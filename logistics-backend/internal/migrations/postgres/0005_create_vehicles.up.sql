@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS vehicles (
+    id          BIGSERIAL PRIMARY KEY,
+    driver_id   BIGINT           NOT NULL UNIQUE REFERENCES users (id),
+    current_lat DOUBLE PRECISION NOT NULL,
+    current_lng DOUBLE PRECISION NOT NULL,
+    last_update TIMESTAMPTZ      NULL DEFAULT NOW()
+);
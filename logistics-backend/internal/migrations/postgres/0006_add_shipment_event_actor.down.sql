@@ -0,0 +1,4 @@
+ALTER TABLE shipment_events
+    DROP COLUMN IF EXISTS reason,
+    DROP COLUMN IF EXISTS actor_role,
+    DROP COLUMN IF EXISTS actor_id;
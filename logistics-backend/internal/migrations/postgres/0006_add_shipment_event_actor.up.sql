@@ -0,0 +1,4 @@
+ALTER TABLE shipment_events
+    ADD COLUMN IF NOT EXISTS actor_id   BIGINT       NULL,
+    ADD COLUMN IF NOT EXISTS actor_role VARCHAR(32)  NULL,
+    ADD COLUMN IF NOT EXISTS reason     VARCHAR(255) NULL;
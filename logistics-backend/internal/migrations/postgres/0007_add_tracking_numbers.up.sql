@@ -0,0 +1,6 @@
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS code VARCHAR(8) NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_warehouses_code ON warehouses (code);
+CREATE TABLE IF NOT EXISTS sequences (
+    name       VARCHAR(64) PRIMARY KEY,
+    last_value BIGINT      NOT NULL
+);
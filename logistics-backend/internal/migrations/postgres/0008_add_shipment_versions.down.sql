@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS shipment_changes;
+ALTER TABLE shipments DROP COLUMN IF EXISTS version;
@@ -0,0 +1,13 @@
+ALTER TABLE shipments ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+CREATE TABLE IF NOT EXISTS shipment_changes (
+    id          BIGSERIAL PRIMARY KEY,
+    shipment_id BIGINT       NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+    version     BIGINT       NOT NULL,
+    field       VARCHAR(64)  NOT NULL,
+    old_value   VARCHAR(512) NULL,
+    new_value   VARCHAR(512) NULL,
+    actor_id    BIGINT       NULL,
+    actor_role  VARCHAR(32)  NULL,
+    changed_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_shipment_changes_shipment ON shipment_changes (shipment_id, id);
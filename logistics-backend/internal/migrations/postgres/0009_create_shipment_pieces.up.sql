@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS shipment_pieces (
+    id             BIGSERIAL PRIMARY KEY,
+    shipment_id    BIGINT           NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+    piece_number   INTEGER          NOT NULL,
+    barcode        VARCHAR(80)      NOT NULL,
+    description    VARCHAR(255)     NULL,
+    weight_kg      DOUBLE PRECISION NULL,
+    length_cm      DOUBLE PRECISION NULL,
+    width_cm       DOUBLE PRECISION NULL,
+    height_cm      DOUBLE PRECISION NULL,
+    declared_value DOUBLE PRECISION NULL,
+    status         VARCHAR(32)      NOT NULL DEFAULT 'pending',
+    created_at     TIMESTAMPTZ      NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ      NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipment_pieces_barcode ON shipment_pieces (barcode);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipment_pieces_number ON shipment_pieces (shipment_id, piece_number);
+INSERT INTO shipment_pieces (shipment_id, piece_number, barcode, status)
+SELECT id, 1, tracking_number || '-001', status FROM shipments;
+ALTER TABLE shipment_events
+    ADD COLUMN IF NOT EXISTS piece_id BIGINT NULL REFERENCES shipment_pieces (id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_shipment_events_piece ON shipment_events (piece_id);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS delivery_proofs (
+    id             BIGSERIAL PRIMARY KEY,
+    shipment_id    BIGINT           NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+    event_id       BIGINT           NOT NULL REFERENCES shipment_events (id) ON DELETE CASCADE,
+    recipient_name VARCHAR(255)     NOT NULL,
+    signature_key  VARCHAR(255)     NOT NULL,
+    signature_type VARCHAR(64)      NOT NULL,
+    photo_key      VARCHAR(255)     NULL,
+    photo_type     VARCHAR(64)      NULL,
+    latitude       DOUBLE PRECISION NOT NULL,
+    longitude      DOUBLE PRECISION NOT NULL,
+    created_at     TIMESTAMPTZ      NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_delivery_proofs_shipment ON delivery_proofs (shipment_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_delivery_proofs_event ON delivery_proofs (event_id);
@@ -0,0 +1 @@
+ALTER TABLE shipments ADD COLUMN IF NOT EXISTS needs_manual_pin BOOLEAN NOT NULL DEFAULT FALSE;
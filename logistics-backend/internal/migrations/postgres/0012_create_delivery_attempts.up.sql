@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS delivery_attempts (
+    id              BIGSERIAL PRIMARY KEY,
+    shipment_id     BIGINT           NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+    attempt_number  INTEGER          NOT NULL,
+    event_id        BIGINT           NOT NULL REFERENCES shipment_events (id) ON DELETE CASCADE,
+    reason          VARCHAR(32)      NOT NULL,
+    notes           VARCHAR(512)     NULL,
+    next_attempt_on TIMESTAMPTZ      NULL,
+    latitude        DOUBLE PRECISION NULL,
+    longitude       DOUBLE PRECISION NULL,
+    actor_id        BIGINT           NULL,
+    actor_role      VARCHAR(32)      NULL,
+    attempted_at    TIMESTAMPTZ      NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_delivery_attempts_number ON delivery_attempts (shipment_id, attempt_number);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS shipment_returns (
+    id                   BIGSERIAL PRIMARY KEY,
+    rma_number           VARCHAR(32)      NOT NULL,
+    original_shipment_id BIGINT           NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+    return_shipment_id   BIGINT           NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+    reason               VARCHAR(32)      NOT NULL,
+    notes                VARCHAR(512)     NULL,
+    pickup_address       VARCHAR(512)     NOT NULL,
+    pickup_latitude      DOUBLE PRECISION NULL,
+    pickup_longitude     DOUBLE PRECISION NULL,
+    requested_by         BIGINT           NULL,
+    requested_role       VARCHAR(32)      NULL,
+    created_at           TIMESTAMPTZ      NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipment_returns_rma ON shipment_returns (rma_number);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipment_returns_original ON shipment_returns (original_shipment_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipment_returns_return ON shipment_returns (return_shipment_id);
@@ -0,0 +1,3 @@
+ALTER TABLE shipment_events
+    DROP COLUMN IF EXISTS scanned_at,
+    DROP COLUMN IF EXISTS scan_type;
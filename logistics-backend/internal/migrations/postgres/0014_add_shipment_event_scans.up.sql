@@ -0,0 +1,3 @@
+ALTER TABLE shipment_events
+    ADD COLUMN IF NOT EXISTS scan_type  VARCHAR(32) NULL,
+    ADD COLUMN IF NOT EXISTS scanned_at TIMESTAMPTZ NULL;
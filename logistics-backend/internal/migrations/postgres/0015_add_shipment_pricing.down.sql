@@ -0,0 +1,5 @@
+ALTER TABLE shipments
+    DROP COLUMN IF EXISTS currency,
+    DROP COLUMN IF EXISTS price,
+    DROP COLUMN IF EXISTS chargeable_weight_kg,
+    DROP COLUMN IF EXISTS service_level;
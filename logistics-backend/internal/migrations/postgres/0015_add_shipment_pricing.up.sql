@@ -0,0 +1,5 @@
+ALTER TABLE shipments
+    ADD COLUMN IF NOT EXISTS service_level        VARCHAR(32)      NULL,
+    ADD COLUMN IF NOT EXISTS chargeable_weight_kg DOUBLE PRECISION NULL,
+    ADD COLUMN IF NOT EXISTS price                DOUBLE PRECISION NULL,
+    ADD COLUMN IF NOT EXISTS currency             CHAR(3)          NULL;
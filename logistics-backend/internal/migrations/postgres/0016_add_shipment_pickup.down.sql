@@ -0,0 +1,4 @@
+ALTER TABLE shipments
+    DROP COLUMN IF EXISTS pickup_longitude,
+    DROP COLUMN IF EXISTS pickup_latitude,
+    DROP COLUMN IF EXISTS pickup_address;
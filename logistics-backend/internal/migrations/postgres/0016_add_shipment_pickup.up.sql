@@ -0,0 +1,4 @@
+ALTER TABLE shipments
+    ADD COLUMN IF NOT EXISTS pickup_address   VARCHAR(512)     NULL,
+    ADD COLUMN IF NOT EXISTS pickup_latitude  DOUBLE PRECISION NULL,
+    ADD COLUMN IF NOT EXISTS pickup_longitude DOUBLE PRECISION NULL;
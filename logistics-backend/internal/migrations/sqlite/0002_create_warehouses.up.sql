@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS warehouses (
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    name      TEXT NOT NULL,
+    latitude  REAL NOT NULL,
+    longitude REAL NOT NULL
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS shipments (
+    id                    INTEGER PRIMARY KEY AUTOINCREMENT,
+    tracking_number       TEXT      NOT NULL UNIQUE,
+    origin_warehouse_id   INTEGER   NOT NULL REFERENCES warehouses (id),
+    destination_address   TEXT      NOT NULL,
+    destination_latitude  REAL      NULL,
+    destination_longitude REAL      NULL,
+    customer_id           INTEGER   NOT NULL REFERENCES users (id),
+    driver_id             INTEGER   NULL REFERENCES users (id),
+    status                TEXT      NOT NULL DEFAULT 'pending',
+    created_at            TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at            TIMESTAMP NULL
+);
+CREATE INDEX IF NOT EXISTS idx_shipments_customer ON shipments (customer_id);
+CREATE INDEX IF NOT EXISTS idx_shipments_driver ON shipments (driver_id);
+CREATE INDEX IF NOT EXISTS idx_shipments_status ON shipments (status);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS shipment_events (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    shipment_id INTEGER   NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+    status      TEXT      NOT NULL,
+    latitude    REAL      NULL,
+    longitude   REAL      NULL,
+    timestamp   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_shipment_events_shipment_time ON shipment_events (shipment_id, timestamp);
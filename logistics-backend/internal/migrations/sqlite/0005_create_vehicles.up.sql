@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS vehicles (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    driver_id   INTEGER   NOT NULL UNIQUE REFERENCES users (id),
+    current_lat REAL      NOT NULL,
+    current_lng REAL      NOT NULL,
+    last_update TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP
+);
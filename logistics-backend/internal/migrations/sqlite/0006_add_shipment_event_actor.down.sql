@@ -0,0 +1,3 @@
+ALTER TABLE shipment_events DROP COLUMN reason;
+ALTER TABLE shipment_events DROP COLUMN actor_role;
+ALTER TABLE shipment_events DROP COLUMN actor_id;
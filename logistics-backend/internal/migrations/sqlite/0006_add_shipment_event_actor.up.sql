@@ -0,0 +1,3 @@
+ALTER TABLE shipment_events ADD COLUMN actor_id INTEGER NULL;
+ALTER TABLE shipment_events ADD COLUMN actor_role TEXT NULL;
+ALTER TABLE shipment_events ADD COLUMN reason TEXT NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS sequences;
+DROP INDEX IF EXISTS idx_warehouses_code;
+ALTER TABLE warehouses DROP COLUMN code;
@@ -0,0 +1,6 @@
+ALTER TABLE warehouses ADD COLUMN code TEXT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_warehouses_code ON warehouses (code);
+CREATE TABLE IF NOT EXISTS sequences (
+    name       TEXT    PRIMARY KEY,
+    last_value INTEGER NOT NULL
+);
@@ -0,0 +1,13 @@
+ALTER TABLE shipments ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+CREATE TABLE IF NOT EXISTS shipment_changes (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    shipment_id INTEGER   NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+    version     INTEGER   NOT NULL,
+    field       TEXT      NOT NULL,
+    old_value   TEXT      NULL,
+    new_value   TEXT      NULL,
+    actor_id    INTEGER   NULL,
+    actor_role  TEXT      NULL,
+    changed_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_shipment_changes_shipment ON shipment_changes (shipment_id, id);
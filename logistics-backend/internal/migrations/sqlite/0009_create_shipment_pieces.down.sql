@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_shipment_events_piece;
+ALTER TABLE shipment_events DROP COLUMN piece_id;
+DROP TABLE IF EXISTS shipment_pieces;
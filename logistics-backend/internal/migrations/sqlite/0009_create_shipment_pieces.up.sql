@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS shipment_pieces (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    shipment_id    INTEGER   NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+    piece_number   INTEGER   NOT NULL,
+    barcode        TEXT      NOT NULL,
+    description    TEXT      NULL,
+    weight_kg      REAL      NULL,
+    length_cm      REAL      NULL,
+    width_cm       REAL      NULL,
+    height_cm      REAL      NULL,
+    declared_value REAL      NULL,
+    status         TEXT      NOT NULL DEFAULT 'pending',
+    created_at     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMP NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipment_pieces_barcode ON shipment_pieces (barcode);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipment_pieces_number ON shipment_pieces (shipment_id, piece_number);
+INSERT INTO shipment_pieces (shipment_id, piece_number, barcode, status)
+SELECT id, 1, tracking_number || '-001', status FROM shipments;
+ALTER TABLE shipment_events ADD COLUMN piece_id INTEGER NULL;
+CREATE INDEX IF NOT EXISTS idx_shipment_events_piece ON shipment_events (piece_id);
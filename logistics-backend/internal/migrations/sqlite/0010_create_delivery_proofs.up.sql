@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS delivery_proofs (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    shipment_id    INTEGER   NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+    event_id       INTEGER   NOT NULL REFERENCES shipment_events (id) ON DELETE CASCADE,
+    recipient_name TEXT      NOT NULL,
+    signature_key  TEXT      NOT NULL,
+    signature_type TEXT      NOT NULL,
+    photo_key      TEXT      NULL,
+    photo_type     TEXT      NULL,
+    latitude       REAL      NOT NULL,
+    longitude      REAL      NOT NULL,
+    created_at     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_delivery_proofs_shipment ON delivery_proofs (shipment_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_delivery_proofs_event ON delivery_proofs (event_id);
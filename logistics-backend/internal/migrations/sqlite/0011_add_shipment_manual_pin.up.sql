@@ -0,0 +1 @@
+ALTER TABLE shipments ADD COLUMN needs_manual_pin INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS delivery_attempts (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    shipment_id     INTEGER   NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+    attempt_number  INTEGER   NOT NULL,
+    event_id        INTEGER   NOT NULL REFERENCES shipment_events (id) ON DELETE CASCADE,
+    reason          TEXT      NOT NULL,
+    notes           TEXT      NULL,
+    next_attempt_on TIMESTAMP NULL,
+    latitude        REAL      NULL,
+    longitude       REAL      NULL,
+    actor_id        INTEGER   NULL,
+    actor_role      TEXT      NULL,
+    attempted_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_delivery_attempts_number ON delivery_attempts (shipment_id, attempt_number);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS shipment_returns (
+    id                   INTEGER PRIMARY KEY AUTOINCREMENT,
+    rma_number           TEXT      NOT NULL,
+    original_shipment_id INTEGER   NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+    return_shipment_id   INTEGER   NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+    reason               TEXT      NOT NULL,
+    notes                TEXT      NULL,
+    pickup_address       TEXT      NOT NULL,
+    pickup_latitude      REAL      NULL,
+    pickup_longitude     REAL      NULL,
+    requested_by         INTEGER   NULL,
+    requested_role       TEXT      NULL,
+    created_at           TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipment_returns_rma ON shipment_returns (rma_number);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipment_returns_original ON shipment_returns (original_shipment_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipment_returns_return ON shipment_returns (return_shipment_id);
@@ -0,0 +1,2 @@
+ALTER TABLE shipment_events DROP COLUMN scanned_at;
+ALTER TABLE shipment_events DROP COLUMN scan_type;
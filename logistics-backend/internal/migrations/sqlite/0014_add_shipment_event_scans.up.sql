@@ -0,0 +1,2 @@
+ALTER TABLE shipment_events ADD COLUMN scan_type TEXT NULL;
+ALTER TABLE shipment_events ADD COLUMN scanned_at TIMESTAMP NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE shipments DROP COLUMN currency;
+ALTER TABLE shipments DROP COLUMN price;
+ALTER TABLE shipments DROP COLUMN chargeable_weight_kg;
+ALTER TABLE shipments DROP COLUMN service_level;
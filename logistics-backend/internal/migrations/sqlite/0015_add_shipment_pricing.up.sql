@@ -0,0 +1,4 @@
+ALTER TABLE shipments ADD COLUMN service_level TEXT NULL;
+ALTER TABLE shipments ADD COLUMN chargeable_weight_kg REAL NULL;
+ALTER TABLE shipments ADD COLUMN price REAL NULL;
+ALTER TABLE shipments ADD COLUMN currency TEXT NULL;
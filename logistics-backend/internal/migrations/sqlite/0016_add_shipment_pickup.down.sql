@@ -0,0 +1,3 @@
+ALTER TABLE shipments DROP COLUMN pickup_longitude;
+ALTER TABLE shipments DROP COLUMN pickup_latitude;
+ALTER TABLE shipments DROP COLUMN pickup_address;
@@ -0,0 +1,3 @@
+ALTER TABLE shipments ADD COLUMN pickup_address TEXT NULL;
+ALTER TABLE shipments ADD COLUMN pickup_latitude REAL NULL;
+ALTER TABLE shipments ADD COLUMN pickup_longitude REAL NULL;
@@ -0,0 +1,216 @@
+// Package pricing computes shipping charges from a rate card of distance
+// zones, service levels and weight rates.
+package pricing
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// bundledRates is the rate card used unless a deployment loads its own with
+// LoadRateCard.
+//
+//go:embed rates.yaml
+var bundledRates string
+
+var (
+	ErrUnknownServiceLevel = errors.New("unknown service level")
+	// ErrOutOfArea means the distance exceeds the last zone of the card.
+	ErrOutOfArea = errors.New("destination is outside the service area")
+)
+
+// RateCard prices shipments. A shipment is charged by the zone its distance
+// falls into, its service level and its chargeable weight: the actual or
+// the volumetric weight of each piece, whichever is higher, summed and
+// rounded up to a whole weight step.
+type RateCard struct {
+	Currency string `yaml:"currency"`
+	// VolumetricDivisor is the volume in cubic centimetres billed as one
+	// kilogram.
+	VolumetricDivisor float64 `yaml:"volumetric_divisor"`
+	// WeightStepKg is the granularity weight is charged in, and the weight
+	// a zone's base rate covers.
+	WeightStepKg        float64  `yaml:"weight_step_kg"`
+	ServiceLevels       []string `yaml:"service_levels"`
+	DefaultServiceLevel string   `yaml:"default_service_level"`
+	// Zones are ordered by distance.
+	Zones []Zone `yaml:"zones"`
+}
+
+// Zone is a band of distance from the origin warehouse.
+type Zone struct {
+	Name string `yaml:"name"`
+	// MaxKm is the furthest straight-line distance the zone covers. Zero,
+	// allowed on the last zone only, means no limit.
+	MaxKm float64 `yaml:"max_km"`
+	// Rates holds a rate for every service level of the card.
+	Rates map[string]Rate `yaml:"rates"`
+}
+
+// Rate charges Base for the first weight step and PerKg for every
+// kilogram beyond it.
+type Rate struct {
+	Base  float64 `yaml:"base"`
+	PerKg float64 `yaml:"per_kg"`
+}
+
+// Parcel is one piece to price. Zero dimensions or weight are unknown and
+// count as nothing.
+type Parcel struct {
+	WeightKg                    float64
+	LengthCm, WidthCm, HeightCm float64
+}
+
+// Quote is a computed charge with the figures that went into it. Weights
+// are in kilograms and amounts in Currency.
+type Quote struct {
+	ServiceLevel       string  `json:"service_level"`
+	Zone               string  `json:"zone"`
+	DistanceKm         float64 `json:"distance_km"`
+	ActualWeightKg     float64 `json:"actual_weight_kg"`
+	VolumetricWeightKg float64 `json:"volumetric_weight_kg"`
+	ChargeableWeightKg float64 `json:"chargeable_weight_kg"`
+	BaseCharge         float64 `json:"base_charge"`
+	WeightCharge       float64 `json:"weight_charge"`
+	Total              float64 `json:"total"`
+	Currency           string  `json:"currency"`
+}
+
+// LoadRateCard reads a YAML rate card from path, or the bundled one when
+// path is empty.
+func LoadRateCard(path string) (*RateCard, error) {
+	if path == "" {
+		return NewRateCard(strings.NewReader(bundledRates))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	card, err := NewRateCard(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return card, nil
+}
+
+// NewRateCard reads and validates a YAML rate card. Unknown keys are
+// rejected, so a misspelt one does not silently fall back to zero.
+func NewRateCard(r io.Reader) (*RateCard, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	var card RateCard
+	if err := dec.Decode(&card); err != nil {
+		return nil, fmt.Errorf("reading rate card: %w", err)
+	}
+	if err := card.validate(); err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (c *RateCard) validate() error {
+	var problems []string
+	if len(c.Currency) != 3 {
+		problems = append(problems, "currency must be a three-letter code")
+	}
+	if c.VolumetricDivisor <= 0 {
+		problems = append(problems, "volumetric_divisor must be positive")
+	}
+	if c.WeightStepKg <= 0 {
+		problems = append(problems, "weight_step_kg must be positive")
+	}
+	if len(c.ServiceLevels) == 0 {
+		problems = append(problems, "service_levels must not be empty")
+	}
+	if !slices.Contains(c.ServiceLevels, c.DefaultServiceLevel) {
+		problems = append(problems, "default_service_level must be one of service_levels")
+	}
+	if len(c.Zones) == 0 {
+		problems = append(problems, "zones must not be empty")
+	}
+	for i, z := range c.Zones {
+		last := i == len(c.Zones)-1
+		switch {
+		case z.Name == "":
+			problems = append(problems, fmt.Sprintf("zone %d has no name", i+1))
+		case z.MaxKm < 0, z.MaxKm == 0 && !last:
+			problems = append(problems, fmt.Sprintf("zone %s: max_km must be positive", z.Name))
+		case i > 0 && z.MaxKm != 0 && z.MaxKm <= c.Zones[i-1].MaxKm:
+			problems = append(problems, fmt.Sprintf("zone %s: max_km must exceed that of the zone before", z.Name))
+		}
+		for _, level := range c.ServiceLevels {
+			rate, ok := z.Rates[level]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("zone %s has no %s rate", z.Name, level))
+			} else if rate.Base < 0 || rate.PerKg < 0 {
+				problems = append(problems, fmt.Sprintf("zone %s: %s rate must not be negative", z.Name, level))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid rate card: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// zone finds the zone covering a distance.
+func (c *RateCard) zone(distanceKm float64) (Zone, bool) {
+	for _, z := range c.Zones {
+		if z.MaxKm == 0 || distanceKm <= z.MaxKm {
+			return z, true
+		}
+	}
+	return Zone{}, false
+}
+
+// Quote prices parcels sent distanceKm at a service level, the card's
+// default if level is empty. It fails with ErrUnknownServiceLevel or
+// ErrOutOfArea.
+func (c *RateCard) Quote(distanceKm float64, level string, parcels []Parcel) (*Quote, error) {
+	if level == "" {
+		level = c.DefaultServiceLevel
+	}
+	if !slices.Contains(c.ServiceLevels, level) {
+		return nil, ErrUnknownServiceLevel
+	}
+	zone, ok := c.zone(distanceKm)
+	if !ok {
+		return nil, ErrOutOfArea
+	}
+
+	q := &Quote{ServiceLevel: level, Zone: zone.Name, DistanceKm: round(distanceKm, 1), Currency: c.Currency}
+	var chargeable float64
+	for _, p := range parcels {
+		volumetric := p.LengthCm * p.WidthCm * p.HeightCm / c.VolumetricDivisor
+		q.ActualWeightKg += p.WeightKg
+		q.VolumetricWeightKg += volumetric
+		chargeable += math.Max(p.WeightKg, volumetric)
+	}
+	// Every shipment pays for at least one step. The tolerance keeps float
+	// noise from rounding an exact multiple up by a whole step.
+	steps := math.Max(1, math.Ceil(chargeable/c.WeightStepKg-1e-9))
+	q.ChargeableWeightKg = round(steps*c.WeightStepKg, 3)
+	q.ActualWeightKg = round(q.ActualWeightKg, 3)
+	q.VolumetricWeightKg = round(q.VolumetricWeightKg, 3)
+
+	rate := zone.Rates[level]
+	q.BaseCharge = round(rate.Base, 2)
+	q.WeightCharge = round((q.ChargeableWeightKg-c.WeightStepKg)*rate.PerKg, 2)
+	q.Total = round(q.BaseCharge+q.WeightCharge, 2)
+	return q, nil
+}
+
+// round rounds v to the given number of decimal places.
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
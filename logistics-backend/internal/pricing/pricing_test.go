@@ -0,0 +1,141 @@
+package pricing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testCard = `
+currency: INR
+volumetric_divisor: 5000
+weight_step_kg: 0.5
+service_levels: [standard, express]
+default_service_level: standard
+zones:
+  - name: local
+    max_km: 50
+    rates:
+      standard: {base: 40, per_kg: 10}
+      express: {base: 80, per_kg: 20}
+  - name: regional
+    max_km: 500
+    rates:
+      standard: {base: 60, per_kg: 20}
+      express: {base: 120, per_kg: 40}
+  - name: national
+    rates:
+      standard: {base: 90, per_kg: 30}
+      express: {base: 170, per_kg: 60}
+`
+
+func card(t *testing.T, yaml string) *RateCard {
+	t.Helper()
+	c, err := NewRateCard(strings.NewReader(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestQuoteChargeableWeight(t *testing.T) {
+	c := card(t, testCard)
+	q, err := c.Quote(10, "standard", []Parcel{
+		// Heavier than its volume: 2 kg against 0.2 kg.
+		{WeightKg: 2, LengthCm: 10, WidthCm: 10, HeightCm: 10},
+		// Bulkier than its weight: 0.5 kg against 12 kg.
+		{WeightKg: 0.5, LengthCm: 50, WidthCm: 40, HeightCm: 30},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each parcel counts at its higher weight, 2 + 12, not the higher of
+	// the totals, 12.2.
+	if q.ActualWeightKg != 2.5 || q.VolumetricWeightKg != 12.2 || q.ChargeableWeightKg != 14 {
+		t.Fatalf("weights %+v, want 2.5 actual, 12.2 volumetric and 14 chargeable", q)
+	}
+	if q.BaseCharge != 40 || q.WeightCharge != 135 || q.Total != 175 {
+		t.Fatalf("charges %+v, want 40 + 13.5 kg at 10", q)
+	}
+
+	// Weight is charged in whole steps, and at least one.
+	for _, tt := range []struct{ weight, want float64 }{{0, 0.5}, {0.5, 0.5}, {0.51, 1}, {1.2, 1.5}} {
+		q, err := c.Quote(10, "standard", []Parcel{{WeightKg: tt.weight}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.ChargeableWeightKg != tt.want {
+			t.Errorf("%v kg charged as %v kg, want %v", tt.weight, q.ChargeableWeightKg, tt.want)
+		}
+	}
+}
+
+func TestQuoteZones(t *testing.T) {
+	c := card(t, testCard)
+	tests := []struct {
+		km   float64
+		zone string
+		base float64
+	}{
+		{0, "local", 40},
+		{50, "local", 40},
+		{50.01, "regional", 60},
+		{500, "regional", 60},
+		{500.01, "national", 90},
+		{20000, "national", 90},
+	}
+	for _, tt := range tests {
+		q, err := c.Quote(tt.km, "standard", nil)
+		if err != nil {
+			t.Fatalf("%v km: %v", tt.km, err)
+		}
+		if q.Zone != tt.zone || q.Total != tt.base {
+			t.Errorf("%v km in zone %s at %v, want %s at %v", tt.km, q.Zone, q.Total, tt.zone, tt.base)
+		}
+	}
+}
+
+func TestQuoteOutOfArea(t *testing.T) {
+	bounded := strings.Replace(testCard, "  - name: national\n", "  - name: national\n    max_km: 1500\n", 1)
+	c := card(t, bounded)
+	if _, err := c.Quote(1500, "standard", nil); err != nil {
+		t.Fatalf("1500 km: %v", err)
+	}
+	if _, err := c.Quote(1500.1, "standard", nil); !errors.Is(err, ErrOutOfArea) {
+		t.Fatalf("1500.1 km: %v, want ErrOutOfArea", err)
+	}
+}
+
+func TestQuoteServiceLevel(t *testing.T) {
+	c := card(t, testCard)
+	q, err := c.Quote(10, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.ServiceLevel != "standard" || q.Total != 40 {
+		t.Fatalf("default level quoted as %s at %v, want standard at 40", q.ServiceLevel, q.Total)
+	}
+	if q, err := c.Quote(10, "express", nil); err != nil || q.Total != 80 {
+		t.Fatalf("express quoted as %+v, %v", q, err)
+	}
+	if _, err := c.Quote(10, "overnight", nil); !errors.Is(err, ErrUnknownServiceLevel) {
+		t.Fatalf("unknown level: %v, want ErrUnknownServiceLevel", err)
+	}
+}
+
+func TestNewRateCardRejects(t *testing.T) {
+	for name, yaml := range map[string]string{
+		"unknown key":      testCard + "discount: 5\n",
+		"missing rate":     strings.Replace(testCard, "      express: {base: 170, per_kg: 60}\n", "", 1),
+		"unordered zones":  strings.Replace(testCard, "max_km: 500", "max_km: 40", 1),
+		"unknown default":  strings.Replace(testCard, "default_service_level: standard", "default_service_level: economy", 1),
+		"unbounded middle": strings.Replace(testCard, "    max_km: 50\n", "", 1),
+	} {
+		if _, err := NewRateCard(strings.NewReader(yaml)); err == nil {
+			t.Errorf("%s: card accepted", name)
+		}
+	}
+	if _, err := LoadRateCard(""); err != nil {
+		t.Fatalf("bundled card: %v", err)
+	}
+}
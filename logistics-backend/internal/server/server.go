@@ -0,0 +1,163 @@
+// Package server assembles the HTTP API from its dependencies. It holds no
+// package-level state, so several instances can run side by side.
+package server
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"logistics-backend/internal/auth"
+	"logistics-backend/internal/blob"
+	"logistics-backend/internal/customers"
+	"logistics-backend/internal/drivers"
+	"logistics-backend/internal/geocode"
+	"logistics-backend/internal/health"
+	"logistics-backend/internal/middleware"
+	"logistics-backend/internal/pricing"
+	"logistics-backend/internal/shipments"
+	"logistics-backend/internal/store"
+	"logistics-backend/internal/tracking"
+	"logistics-backend/internal/warehouses"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+type Config struct {
+	// JWTSecret signs and verifies access tokens.
+	JWTSecret string
+	// AccessTokenTTL is the lifetime of tokens issued at login.
+	AccessTokenTTL time.Duration
+	// AllowOrigins lists the browser origins allowed by CORS. CORS is not
+	// enabled when it is empty.
+	AllowOrigins []string
+	// TrustedProxies may set X-Forwarded-For. With none, client IPs are
+	// taken from the connection, so they cannot be spoofed past the
+	// tracking rate limit.
+	TrustedProxies []string
+	// RequestTimeout bounds each request's context, and with it every query
+	// the request runs. Zero disables the limit.
+	RequestTimeout time.Duration
+	// ExportTimeout does the same for shipment exports.
+	ExportTimeout time.Duration
+	// TrackingFormat lays out the tracking numbers of new shipments. Public
+	// tracking looks up only numbers it finds Trackable.
+	TrackingFormat *tracking.Format
+	// TrackRateLimit and TrackRateBurst limit public tracking lookups per
+	// client IP, in requests per minute.
+	TrackRateLimit int
+	TrackRateBurst int
+	// MaxDeliveryAttempts is the number of failed delivery attempts after
+	// which a shipment returns to origin.
+	MaxDeliveryAttempts int
+}
+
+type Deps struct {
+	Stores *store.Stores
+	// Blobs keeps uploaded files such as proof-of-delivery images.
+	Blobs blob.Store
+	// Geocoder places the destination addresses of shipments. It is
+	// optional; without it shipments keep the coordinates they are given.
+	Geocoder geocode.Geocoder
+	// Rates prices shipments. It is optional; without it shipments are
+	// booked unpriced and quotes are refused.
+	Rates *pricing.RateCard
+	// DB and Schema feed the readiness probe. Both are optional; leave them
+	// nil when Stores is not backed by SQL.
+	DB     *sql.DB
+	Schema health.SchemaVersioner
+}
+
+// New builds the Gin engine with every route registered.
+func New(cfg Config, deps Deps) (http.Handler, error) {
+	authHandler := auth.NewHandler(deps.Stores.Users, cfg.JWTSecret, cfg.AccessTokenTTL)
+	trackingNumbers := tracking.NewGenerator(cfg.TrackingFormat, deps.Stores.Sequences)
+	shipmentHandler := shipments.NewHandler(deps.Stores, trackingNumbers, shipments.Options{
+		Blobs:       deps.Blobs,
+		Geocoder:    deps.Geocoder,
+		MaxAttempts: cfg.MaxDeliveryAttempts,
+		Rates:       deps.Rates,
+	})
+	warehouseHandler := warehouses.NewHandler(deps.Stores.Warehouses)
+	driverHandler := drivers.NewHandler(deps.Stores.Users)
+	customerHandler := customers.NewHandler(deps.Stores.Users)
+	healthHandler := health.NewHandler(deps.DB, deps.Schema)
+
+	authorize := func(roles ...string) gin.HandlerFunc {
+		return middleware.AuthMiddleware(cfg.JWTSecret, roles...)
+	}
+	timeout := func(d time.Duration) gin.HandlerFunc {
+		if d <= 0 {
+			return func(c *gin.Context) { c.Next() }
+		}
+		return middleware.Timeout(d)
+	}
+
+	r := gin.Default()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	// CORS middleware
+	if len(cfg.AllowOrigins) > 0 {
+		corsConfig := cors.DefaultConfig()
+		corsConfig.AllowOrigins = cfg.AllowOrigins
+		corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+		corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "If-Match"}
+		corsConfig.ExposeHeaders = []string{"X-Total-Count", "X-Next-Cursor", "ETag"}
+		r.Use(cors.New(corsConfig))
+	}
+
+	// Probes
+	r.GET("/healthz", healthHandler.Liveness)
+	r.GET("/readyz", healthHandler.Readiness)
+
+	// Public routes
+	public := r.Group("/", timeout(cfg.RequestTimeout))
+	public.POST("/auth/register", authHandler.Register)
+	public.POST("/auth/login", authHandler.Login)
+	public.GET("/track/:tracking_number", middleware.RateLimit(cfg.TrackRateLimit, cfg.TrackRateBurst), shipmentHandler.TrackShipment)
+
+	// Protected routes
+	api := r.Group("/api", timeout(cfg.RequestTimeout))
+	{
+		api.POST("/shipments", authorize("manager"), shipmentHandler.CreateShipment)
+		api.GET("/shipments", authorize("customer", "manager", "driver"), shipmentHandler.GetShipments)
+		api.POST("/shipments/import", authorize("manager"), shipmentHandler.ImportShipments)
+		api.GET("/getWarehouses", authorize("manager", "driver"), warehouseHandler.GetWarehouses)
+		api.GET("/getDrivers", authorize("manager"), driverHandler.GetDrivers)
+		api.GET("/getCustomers", authorize("manager"), customerHandler.GetCustomers)
+		api.PUT("/shipments/:id/status", authorize("manager", "driver"), shipmentHandler.UpdateShipmentStatus)
+		api.PUT("/shipments/:id/assign", authorize("manager"), shipmentHandler.AssignShipmentToCourier)
+		api.PATCH("/shipments/:id", authorize("manager"), shipmentHandler.PatchShipment)
+		api.GET("/shipments/:id/changes", authorize("manager"), shipmentHandler.GetShipmentChanges)
+		api.POST("/shipments/:id/proof-of-delivery", authorize("manager", "driver"), shipmentHandler.SubmitProofOfDelivery)
+		api.GET("/shipments/:id/proof-of-delivery", authorize("customer", "manager"), shipmentHandler.GetProofOfDelivery)
+		api.GET("/shipments/:id/proof-of-delivery/:image", authorize("customer", "manager"), shipmentHandler.DownloadProofImage)
+		api.POST("/shipments/:id/attempts", authorize("manager", "driver"), shipmentHandler.RecordDeliveryAttempt)
+		api.GET("/shipments/:id/attempts", authorize("customer", "manager", "driver"), shipmentHandler.GetDeliveryAttempts)
+		api.GET("/shipments/reattempts", authorize("manager"), shipmentHandler.GetReattempts)
+		api.POST("/shipments/:id/return", authorize("customer", "manager"), shipmentHandler.CreateShipmentReturn)
+		api.GET("/shipments/:id/return", authorize("customer", "manager", "driver"), shipmentHandler.GetShipmentReturn)
+		api.GET("/returns/:rma", authorize("customer", "manager"), shipmentHandler.GetReturnByRMA)
+		api.GET("/shipments/:id/label", authorize("customer", "manager", "driver"), shipmentHandler.GetShipmentLabel)
+		api.GET("/shipments/labels", authorize("manager"), shipmentHandler.GetShipmentLabels)
+		api.GET("/shipments/:id/pieces", authorize("customer", "manager", "driver"), shipmentHandler.GetShipmentPieces)
+		api.POST("/quotes", authorize("customer", "manager"), shipmentHandler.CreateQuote)
+		api.POST("/scans", authorize("manager", "driver"), shipmentHandler.RecordScan)
+		api.GET("/pieces/:barcode", authorize("customer", "manager", "driver"), shipmentHandler.GetPiece)
+		api.PUT("/pieces/:barcode/status", authorize("manager", "driver"), shipmentHandler.UpdatePieceStatus)
+		api.GET("/me", authorize(), authHandler.GetUserDetails)
+		api.GET("/getShipments/:id", authorize("customer", "manager", "driver"), shipmentHandler.GetShipmentData)
+		api.GET("/getShipmentCoordinates", authorize("customer", "manager", "driver"), shipmentHandler.GetShipmentCoordinates)
+		api.GET("/shipments/:id/events", authorize("customer", "manager", "driver"), shipmentHandler.GetShipmentEvents)
+		api.GET("/getShipmentCoordinatesById/:id", authorize("customer", "manager", "driver"), shipmentHandler.GetShipmentCoordinatesByShipmentID)
+	}
+
+	// Exports stream whole tables and get a deadline of their own.
+	exports := r.Group("/api", timeout(cfg.ExportTimeout))
+	exports.GET("/shipments/export", authorize("manager"), shipmentHandler.ExportShipments)
+
+	return r, nil
+}
@@ -0,0 +1,304 @@
+package shipments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"logistics-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Reasons a delivery attempt fails for.
+const (
+	ReasonCustomerAbsent  = "customer_absent"
+	ReasonAddressNotFound = "address_not_found"
+	ReasonRefused         = "refused"
+)
+
+var attemptReasons = []string{ReasonCustomerAbsent, ReasonAddressNotFound, ReasonRefused}
+
+var ErrUnknownReason = errors.New("unknown delivery failure reason")
+
+// Attempt is a failed delivery attempt as reported by a driver.
+type Attempt struct {
+	ShipmentID int64
+	Reason     string
+	Notes      string
+	// NextAttemptOn schedules the reattempt; zero means the next day.
+	NextAttemptOn time.Time
+	ActorID       int64
+	ActorRole     string
+	Latitude      *float64
+	Longitude     *float64
+}
+
+// day truncates t to midnight UTC.
+func day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// RecordAttempt records a failed attempt to deliver a shipment in transit
+// and moves it to failed until its reattempt. The attempt that reaches
+// Options.MaxAttempts moves it on to return_to_origin instead and schedules
+// nothing. It fails like ChangeStatus, or with ErrUnknownReason.
+func (s *Service) RecordAttempt(ctx context.Context, a Attempt) (*store.DeliveryAttempt, error) {
+	if !slices.Contains(attemptReasons, a.Reason) {
+		return nil, ErrUnknownReason
+	}
+
+	var record *store.DeliveryAttempt
+	err := s.stores.Tx.WithinTx(ctx, func(tx *store.Stores) error {
+		svc := s.withStores(tx)
+		event, err := svc.ChangeStatus(ctx, Transition{
+			ShipmentID: a.ShipmentID,
+			To:         StatusFailed,
+			ActorID:    a.ActorID,
+			ActorRole:  a.ActorRole,
+			Reason:     a.Reason,
+			Latitude:   a.Latitude,
+			Longitude:  a.Longitude,
+		})
+		if err != nil {
+			return err
+		}
+		previous, err := tx.Attempts.ListByShipment(ctx, a.ShipmentID)
+		if err != nil {
+			return err
+		}
+
+		actorID := a.ActorID
+		record = &store.DeliveryAttempt{
+			ShipmentID: a.ShipmentID,
+			Number:     len(previous) + 1,
+			EventID:    event.ID,
+			Reason:     a.Reason,
+			Notes:      a.Notes,
+			Latitude:   a.Latitude,
+			Longitude:  a.Longitude,
+			ActorID:    &actorID,
+			ActorRole:  a.ActorRole,
+		}
+		if record.Number >= s.opts.MaxAttempts {
+			_, err = svc.ChangeStatus(ctx, Transition{
+				ShipmentID: a.ShipmentID,
+				To:         StatusReturnToOrigin,
+				ActorID:    a.ActorID,
+				ActorRole:  a.ActorRole,
+				Reason:     fmt.Sprintf("%d delivery attempts failed", record.Number),
+			})
+			if err != nil {
+				return err
+			}
+		} else {
+			next := a.NextAttemptOn
+			if next.IsZero() {
+				next = day(time.Now()).AddDate(0, 0, 1)
+			}
+			record.NextAttemptOn = &next
+		}
+		if err := tx.Attempts.Create(ctx, record); err != nil {
+			return err
+		}
+		// Read the attempt back for the timestamp the database filled in.
+		attempts, err := tx.Attempts.ListByShipment(ctx, a.ShipmentID)
+		if err != nil {
+			return err
+		}
+		record = &attempts[len(attempts)-1]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// RecordDeliveryAttempt records a failed delivery. The body carries the
+// reason code, optional notes, the next_attempt_on date (YYYY-MM-DD,
+// defaulting to tomorrow) and the latitude and longitude of the driver.
+func (h *Handler) RecordDeliveryAttempt(c *gin.Context) {
+	id, ok := shipmentID(c)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Reason        string   `json:"reason"`
+		Notes         string   `json:"notes"`
+		NextAttemptOn string   `json:"next_attempt_on"`
+		Latitude      *float64 `json:"latitude"`
+		Longitude     *float64 `json:"longitude"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invalid := make(map[string]string)
+	if !slices.Contains(attemptReasons, body.Reason) {
+		invalid["reason"] = "must be one of " + strings.Join(attemptReasons, ", ")
+	}
+	notes := strings.TrimSpace(body.Notes)
+	if len(notes) > 512 {
+		invalid["notes"] = "must be at most 512 characters"
+	}
+	var next time.Time
+	if body.NextAttemptOn != "" {
+		var err error
+		if next, err = time.Parse(time.DateOnly, body.NextAttemptOn); err != nil {
+			invalid["next_attempt_on"] = "must be a date like 2006-01-02"
+		} else if next.Before(day(time.Now())) {
+			invalid["next_attempt_on"] = "must not be in the past"
+		}
+	}
+	if (body.Latitude == nil) != (body.Longitude == nil) {
+		invalid["latitude"] = "must be given together with longitude"
+	}
+	if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery attempt", "fields": invalid})
+		return
+	}
+
+	attempt, err := h.Service.RecordAttempt(c.Request.Context(), Attempt{
+		ShipmentID:    id,
+		Reason:        body.Reason,
+		Notes:         notes,
+		NextAttemptOn: next,
+		ActorID:       int64(c.GetInt("user_id")),
+		ActorRole:     c.GetString("role"),
+		Latitude:      body.Latitude,
+		Longitude:     body.Longitude,
+	})
+	var illegal *TransitionError
+	switch {
+	case err == nil:
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return
+	case errors.Is(err, ErrNotAssigned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own shipments"})
+		return
+	case errors.As(err, &illegal):
+		c.JSON(http.StatusConflict, gin.H{"error": "Only shipments in transit can fail delivery", "from": illegal.From, "to": illegal.To})
+		return
+	case errors.Is(err, store.ErrConflict), errors.Is(err, store.ErrDuplicate):
+		c.JSON(http.StatusConflict, gin.H{"error": "Shipment status changed concurrently, please retry"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record delivery attempt", "details": err.Error()})
+		return
+	}
+
+	status := StatusFailed
+	if attempt.NextAttemptOn == nil {
+		status = StatusReturnToOrigin
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message":         "Delivery attempt recorded",
+		"attempt":         attempt,
+		"shipment_status": status,
+	})
+}
+
+// GetDeliveryAttempts lists the failed delivery attempts of a shipment.
+func (h *Handler) GetDeliveryAttempts(c *gin.Context) {
+	id, ok := shipmentID(c)
+	if !ok {
+		return
+	}
+
+	// Customers should only see their own shipment
+	shipment, err := h.visibleShipment(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch delivery attempts"})
+		return
+	}
+	if shipment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return
+	}
+
+	attempts, err := h.Attempts.ListByShipment(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch delivery attempts", "details": err.Error()})
+		return
+	}
+	if attempts == nil {
+		attempts = []store.DeliveryAttempt{}
+	}
+	c.JSON(http.StatusOK, attempts)
+}
+
+// GetReattempts lists the shipments waiting for a reattempt with their last
+// attempt, the earliest scheduled first. It takes the filters of
+// GetShipments, refusing any status but failed, and due=YYYY-MM-DD to keep
+// only reattempts scheduled on or before that day.
+func (h *Handler) GetReattempts(c *gin.Context) {
+	filter, ok := shipmentFilter(c)
+	if !ok {
+		return
+	}
+	if len(filter.Statuses) > 0 && !slices.Equal(filter.Statuses, []string{StatusFailed}) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reattempts are always failed shipments"})
+		return
+	}
+	filter.Statuses = []string{StatusFailed}
+
+	var due time.Time
+	if raw := c.Query("due"); raw != "" {
+		var err error
+		if due, err = time.Parse(time.DateOnly, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "due must be a date like 2006-01-02"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	shipments, err := h.Shipments.List(ctx, store.ShipmentQuery{ShipmentFilter: filter})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reattempts", "details": err.Error()})
+		return
+	}
+	attempts, err := h.Attempts.Latest(ctx, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reattempts", "details": err.Error()})
+		return
+	}
+	last := make(map[int64]store.DeliveryAttempt, len(attempts))
+	for _, a := range attempts {
+		last[a.ShipmentID] = a
+	}
+
+	type reattempt struct {
+		store.Shipment
+		LastAttempt store.DeliveryAttempt `json:"last_attempt"`
+	}
+	out := []reattempt{}
+	for _, sh := range shipments {
+		a := last[sh.ID]
+		if !due.IsZero() && a.NextAttemptOn != nil && a.NextAttemptOn.After(due) {
+			continue
+		}
+		out = append(out, reattempt{sh, a})
+	}
+	slices.SortStableFunc(out, func(a, b reattempt) int {
+		return nextAttempt(a.LastAttempt).Compare(nextAttempt(b.LastAttempt))
+	})
+	c.JSON(http.StatusOK, out)
+}
+
+// nextAttempt is when an attempt scheduled its successor; the zero time if
+// it did not.
+func nextAttempt(a store.DeliveryAttempt) time.Time {
+	if a.NextAttemptOn == nil {
+		return time.Time{}
+	}
+	return *a.NextAttemptOn
+}
@@ -0,0 +1,464 @@
+package shipments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"logistics-backend/internal/pricing"
+	"logistics-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Fields of a shipment that PATCH may change. Status and driver have
+// endpoints of their own.
+const (
+	editOriginWarehouseID    = "origin_warehouse_id"
+	editDestinationAddress   = "destination_address"
+	editDestinationLatitude  = "destination_latitude"
+	editDestinationLongitude = "destination_longitude"
+	editServiceLevel         = "service_level"
+	editCustomerID           = "customer_id"
+)
+
+// editNeedsManualPin is recorded in the change history when an edit raises
+// or clears the flag, but cannot be patched: placing coordinates clears it.
+// Likewise editPrice, worked out again whenever an edit changes one of
+// pricedBy.
+const (
+	editNeedsManualPin = "needs_manual_pin"
+	editPrice          = "price"
+)
+
+// pricedBy lists the editable fields a shipment's price depends on.
+var pricedBy = []string{
+	editOriginWarehouseID, editDestinationAddress, editDestinationLatitude, editDestinationLongitude, editServiceLevel,
+}
+
+var ErrVersionMismatch = errors.New("shipment was modified since it was read")
+
+// ValidationError lists the rejected fields of an edit with the reason for
+// each.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return "invalid fields: " + strings.Join(names, ", ")
+}
+
+// ShipmentPatch holds the fields an edit sets. Nil fields are left alone.
+// SetDestination replaces both destination coordinates, clearing them when
+// they are nil. A new destination address without SetDestination is
+// geocoded.
+type ShipmentPatch struct {
+	OriginWarehouseID    *int64
+	DestinationAddress   *string
+	SetDestination       bool
+	DestinationLatitude  *float64
+	DestinationLongitude *float64
+	ServiceLevel         *string
+	CustomerID           *int64
+
+	// located holds the coordinates Update geocoded for DestinationAddress.
+	located *store.Shipment
+}
+
+// Edit is a requested change to a shipment's details and who asked for it.
+type Edit struct {
+	ShipmentID int64
+	// Version is the version the editor last read; the edit is refused if
+	// the shipment has moved past it.
+	Version   int64
+	Patch     ShipmentPatch
+	ActorID   int64
+	ActorRole string
+}
+
+// Update applies an edit and records a shipment_changes row for every field
+// whose value it changed, all in one transaction. An edit to any of
+// pricedBy prices the shipment again; one that leaves the destination
+// unplaced leaves it unpriced. It returns
+// store.ErrNotFound, ErrVersionMismatch, a *ValidationError or a
+// *TransitionError for shipments that are already delivered or cancelled.
+func (s *Service) Update(ctx context.Context, e Edit) (*store.Shipment, error) {
+	// Geocode before the transaction, so a slow lookup holds no locks.
+	if p := &e.Patch; p.DestinationAddress != nil && !p.SetDestination && s.opts.Geocoder != nil {
+		p.located = &store.Shipment{DestinationAddress: strings.TrimSpace(*p.DestinationAddress)}
+		s.locate(ctx, p.located)
+	}
+
+	var updated *store.Shipment
+	err := s.stores.Tx.WithinTx(ctx, func(tx *store.Stores) error {
+		current, err := tx.Shipments.GetByID(ctx, e.ShipmentID)
+		if err != nil {
+			return err
+		}
+		if current.Version != e.Version {
+			return ErrVersionMismatch
+		}
+		if len(transitions[current.Status]) == 0 {
+			return &TransitionError{From: current.Status, To: current.Status}
+		}
+
+		next := *current
+		if err := s.applyPatch(ctx, tx, &next, e.Patch); err != nil {
+			return err
+		}
+		repriced := slices.ContainsFunc(diffShipments(current, &next), func(c store.ShipmentChange) bool {
+			return slices.Contains(pricedBy, c.Field)
+		})
+		if repriced {
+			err := s.withStores(tx).priceStored(ctx, &next)
+			if errors.Is(err, pricing.ErrOutOfArea) {
+				invalid := map[string]string{editDestinationLatitude: "is outside the service area"}
+				if next.OriginWarehouseID != current.OriginWarehouseID {
+					invalid = map[string]string{editOriginWarehouseID: "does not serve the destination"}
+				}
+				return &ValidationError{Fields: invalid}
+			} else if err != nil {
+				return err
+			}
+		}
+
+		changes := diffShipments(current, &next)
+		if len(changes) == 0 {
+			updated = current
+			return nil
+		}
+		err = tx.Shipments.Update(ctx, &next, e.Version)
+		if errors.Is(err, store.ErrConflict) {
+			return ErrVersionMismatch
+		} else if err != nil {
+			return err
+		}
+
+		actorID := e.ActorID
+		for _, c := range changes {
+			c.ShipmentID = next.ID
+			c.Version = next.Version
+			c.ActorID = &actorID
+			c.ActorRole = e.ActorRole
+			if err := tx.Changes.Create(ctx, &c); err != nil {
+				return err
+			}
+		}
+		updated, err = tx.Shipments.GetByID(ctx, next.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// applyPatch validates p against the stores and the rate card and copies it
+// onto sh.
+func (s *Service) applyPatch(ctx context.Context, tx *store.Stores, sh *store.Shipment, p ShipmentPatch) error {
+	invalid := make(map[string]string)
+
+	if p.OriginWarehouseID != nil {
+		if sh.Status != StatusPending && *p.OriginWarehouseID != sh.OriginWarehouseID {
+			invalid[editOriginWarehouseID] = "can only change while the shipment is pending"
+		} else if _, err := tx.Warehouses.GetByID(ctx, *p.OriginWarehouseID); errors.Is(err, store.ErrNotFound) {
+			invalid[editOriginWarehouseID] = "warehouse does not exist"
+		} else if err != nil {
+			return err
+		} else {
+			sh.OriginWarehouseID = *p.OriginWarehouseID
+		}
+	}
+
+	if p.DestinationAddress != nil {
+		if addr := strings.TrimSpace(*p.DestinationAddress); addr == "" {
+			invalid[editDestinationAddress] = "must not be empty"
+		} else if addr != sh.DestinationAddress {
+			sh.DestinationAddress = addr
+			// The old coordinates belong to the old address.
+			if l := p.located; l != nil {
+				sh.DestinationLatitude, sh.DestinationLongitude = l.DestinationLatitude, l.DestinationLongitude
+				sh.NeedsManualPin = l.NeedsManualPin
+			}
+		}
+	}
+
+	if p.SetDestination {
+		lat, lng := p.DestinationLatitude, p.DestinationLongitude
+		switch {
+		case (lat == nil) != (lng == nil):
+			invalid[editDestinationLatitude] = "must be given together with destination_longitude"
+		case lat != nil && (*lat < -90 || *lat > 90):
+			invalid[editDestinationLatitude] = "must be between -90 and 90"
+		case lng != nil && (*lng < -180 || *lng > 180):
+			invalid[editDestinationLongitude] = "must be between -180 and 180"
+		default:
+			sh.DestinationLatitude, sh.DestinationLongitude = lat, lng
+			sh.NeedsManualPin = lat == nil
+		}
+	}
+
+	if p.ServiceLevel != nil {
+		if level := strings.TrimSpace(*p.ServiceLevel); level == "" {
+			invalid[editServiceLevel] = "must not be empty"
+		} else if err := s.checkServiceLevel(level); err != nil {
+			invalid[editServiceLevel] = "must be one of " + strings.Join(s.opts.Rates.ServiceLevels, ", ")
+		} else {
+			sh.ServiceLevel = level
+		}
+	}
+
+	if p.CustomerID != nil {
+		exists, err := tx.Users.ExistsWithRole(ctx, *p.CustomerID, "customer")
+		if err != nil {
+			return err
+		}
+		if exists {
+			sh.CustomerID = *p.CustomerID
+		} else {
+			invalid[editCustomerID] = "customer does not exist"
+		}
+	}
+
+	if len(invalid) > 0 {
+		return &ValidationError{Fields: invalid}
+	}
+	return nil
+}
+
+// diffShipments returns a change for every editable field that differs
+// between before and after.
+func diffShipments(before, after *store.Shipment) []store.ShipmentChange {
+	id := func(v int64) *string {
+		s := strconv.FormatInt(v, 10)
+		return &s
+	}
+	coord := func(v *float64) *string {
+		if v == nil {
+			return nil
+		}
+		s := strconv.FormatFloat(*v, 'f', -1, 64)
+		return &s
+	}
+	flag := func(v bool) *string {
+		s := strconv.FormatBool(v)
+		return &s
+	}
+
+	var changes []store.ShipmentChange
+	add := func(field string, from, to *string) {
+		if from == nil && to == nil || from != nil && to != nil && *from == *to {
+			return
+		}
+		changes = append(changes, store.ShipmentChange{Field: field, OldValue: from, NewValue: to})
+	}
+	add(editOriginWarehouseID, id(before.OriginWarehouseID), id(after.OriginWarehouseID))
+	add(editDestinationAddress, &before.DestinationAddress, &after.DestinationAddress)
+	add(editDestinationLatitude, coord(before.DestinationLatitude), coord(after.DestinationLatitude))
+	add(editDestinationLongitude, coord(before.DestinationLongitude), coord(after.DestinationLongitude))
+	add(editNeedsManualPin, flag(before.NeedsManualPin), flag(after.NeedsManualPin))
+	add(editServiceLevel, &before.ServiceLevel, &after.ServiceLevel)
+	add(editPrice, coord(before.Price), coord(after.Price))
+	add(editCustomerID, id(before.CustomerID), id(after.CustomerID))
+	return changes
+}
+
+// etag renders a shipment's version as a strong entity tag.
+func etag(sh *store.Shipment) string {
+	return `"` + strconv.FormatInt(sh.Version, 10) + `"`
+}
+
+// ifMatchVersion reads the version from an If-Match header holding one
+// entity tag. Weak tags are accepted, since the version alone decides.
+func ifMatchVersion(header string) (int64, bool) {
+	tag := strings.TrimPrefix(strings.TrimSpace(header), "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	return v, err == nil && v > 0
+}
+
+// parsePatch decodes a JSON merge patch of the editable fields. Fields that
+// are unknown, read-only, null where a value is required or of the wrong
+// type are reported together.
+func parsePatch(body []byte) (ShipmentPatch, error) {
+	var p ShipmentPatch
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return p, err
+	}
+
+	invalid := make(map[string]string)
+	decode := func(field string, v any) bool {
+		if bytes.Equal(raw[field], []byte("null")) {
+			invalid[field] = "must not be null"
+			return false
+		}
+		if err := json.Unmarshal(raw[field], v); err != nil {
+			invalid[field] = "has the wrong type"
+			return false
+		}
+		return true
+	}
+	coordinate := func(field string) *float64 {
+		msg, ok := raw[field]
+		if !ok || bytes.Equal(msg, []byte("null")) {
+			return nil
+		}
+		var v float64
+		if err := json.Unmarshal(msg, &v); err != nil {
+			invalid[field] = "must be a number or null"
+			return nil
+		}
+		return &v
+	}
+
+	for field := range raw {
+		switch field {
+		case editOriginWarehouseID:
+			var v int64
+			if decode(field, &v) {
+				p.OriginWarehouseID = &v
+			}
+		case editDestinationAddress:
+			var v string
+			if decode(field, &v) {
+				p.DestinationAddress = &v
+			}
+		case editServiceLevel:
+			var v string
+			if decode(field, &v) {
+				p.ServiceLevel = &v
+			}
+		case editCustomerID:
+			var v int64
+			if decode(field, &v) {
+				p.CustomerID = &v
+			}
+		case editDestinationLatitude, editDestinationLongitude:
+			p.SetDestination = true
+		case "id", "tracking_number", "status", "driver_id", "version", "created_at", "updated_at", editNeedsManualPin,
+			editPrice, "chargeable_weight_kg", "currency":
+			invalid[field] = "cannot be changed"
+		default:
+			invalid[field] = "is not a shipment field"
+		}
+	}
+	if p.SetDestination {
+		p.DestinationLatitude = coordinate(editDestinationLatitude)
+		p.DestinationLongitude = coordinate(editDestinationLongitude)
+	}
+
+	if len(invalid) > 0 {
+		return p, &ValidationError{Fields: invalid}
+	}
+	return p, nil
+}
+
+// PatchShipment edits a shipment's origin warehouse, destination, service
+// level or customer. The body is a JSON object of the fields to change; the
+// destination coordinates are cleared with null. The If-Match header must
+// carry the shipment's current ETag, and the response carries the new one.
+func (h *Handler) PatchShipment(c *gin.Context) {
+	id, ok := shipmentID(c)
+	if !ok {
+		return
+	}
+
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header with the shipment's ETag is required"})
+		return
+	}
+	version, ok := ifMatchVersion(header)
+	if !ok {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "If-Match must hold a single shipment ETag"})
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body", "details": err.Error()})
+		return
+	}
+	patch, err := parsePatch(body)
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shipment update", "fields": invalid.Fields})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Body must be a JSON object", "details": err.Error()})
+		return
+	}
+
+	shipment, err := h.Service.Update(c.Request.Context(), Edit{
+		ShipmentID: id,
+		Version:    version,
+		Patch:      patch,
+		ActorID:    int64(c.GetInt("user_id")),
+		ActorRole:  c.GetString("role"),
+	})
+	var closed *TransitionError
+	switch {
+	case err == nil:
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return
+	case errors.Is(err, ErrVersionMismatch):
+		if current, getErr := h.Shipments.GetByID(c.Request.Context(), id); getErr == nil {
+			c.Header("ETag", etag(current))
+		}
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Shipment was modified, fetch it again and retry"})
+		return
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shipment update", "fields": invalid.Fields})
+		return
+	case errors.As(err, &closed):
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("cannot edit a %s shipment", closed.From)})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update shipment", "details": err.Error()})
+		return
+	}
+
+	c.Header("ETag", etag(shipment))
+	c.JSON(http.StatusOK, shipment)
+}
+
+// GetShipmentChanges returns the edit history of a shipment, oldest first.
+func (h *Handler) GetShipmentChanges(c *gin.Context) {
+	id, ok := shipmentID(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.Shipments.GetByID(c.Request.Context(), id); errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment changes", "details": err.Error()})
+		return
+	}
+
+	changes, err := h.Changes.List(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment changes", "details": err.Error()})
+		return
+	}
+	if changes == nil {
+		changes = []store.ShipmentChange{}
+	}
+	c.JSON(http.StatusOK, changes)
+}
@@ -0,0 +1,65 @@
+package shipments
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"logistics-backend/internal/eta"
+	"logistics-backend/internal/geo"
+	"logistics-backend/internal/store"
+)
+
+// destination returns where a shipment in transit is headed, or false when
+// no ETA applies to it.
+func destination(sh *store.Shipment) (geo.Point, bool) {
+	if sh.Status != StatusInTransit || sh.DestinationLatitude == nil || sh.DestinationLongitude == nil {
+		return geo.Point{}, false
+	}
+	return geo.Point{Lat: *sh.DestinationLatitude, Lng: *sh.DestinationLongitude}, true
+}
+
+// estimate computes the ETA of one shipment, or nil when it has none.
+func (h *Handler) estimate(ctx context.Context, sh *store.Shipment) (*eta.Estimate, error) {
+	dest, ok := destination(sh)
+	if !ok {
+		return nil, nil
+	}
+	events, err := h.Events.List(ctx, store.EventFilter{ShipmentID: sh.ID})
+	if err != nil {
+		return nil, err
+	}
+	return eta.Compute(dest, events, time.Now()), nil
+}
+
+// estimates computes the ETAs of every shipment matching f that has one.
+func (h *Handler) estimates(ctx context.Context, f store.ShipmentFilter) (map[int64]*eta.Estimate, error) {
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, StatusInTransit) {
+		return nil, nil
+	}
+	f.Statuses = []string{StatusInTransit}
+
+	shipments, err := h.Shipments.List(ctx, store.ShipmentQuery{ShipmentFilter: f})
+	if err != nil {
+		return nil, err
+	}
+	events, err := h.Events.RecentLocated(ctx, f, eta.Samples)
+	if err != nil {
+		return nil, err
+	}
+	byShipment := make(map[int64][]store.ShipmentEvent)
+	for _, e := range events {
+		byShipment[e.ShipmentID] = append(byShipment[e.ShipmentID], e)
+	}
+
+	now := time.Now()
+	estimates := make(map[int64]*eta.Estimate)
+	for i := range shipments {
+		if dest, ok := destination(&shipments[i]); ok {
+			if est := eta.Compute(dest, byShipment[shipments[i].ID], now); est != nil {
+				estimates[shipments[i].ID] = est
+			}
+		}
+	}
+	return estimates, nil
+}
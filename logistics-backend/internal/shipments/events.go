@@ -0,0 +1,83 @@
+package shipments
+
+import (
+	"net/http"
+
+	"logistics-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type eventCursor struct {
+	ID int64 `json:"id"`
+}
+
+// GetShipmentEvents returns a page of a shipment's timeline, oldest first.
+// It accepts status, from, to, limit and cursor query parameters.
+func (h *Handler) GetShipmentEvents(c *gin.Context) {
+	id, ok := shipmentID(c)
+	if !ok {
+		return
+	}
+
+	filter := store.EventFilter{ShipmentID: id, Statuses: listParam(c, "status")}
+	for _, st := range filter.Statuses {
+		if !ValidStatus(st) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status", "details": st})
+			return
+		}
+	}
+	if filter.From, ok = timeParam(c, "from"); !ok {
+		return
+	}
+	if filter.To, ok = timeParam(c, "to"); !ok {
+		return
+	}
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	if raw := c.Query("cursor"); raw != "" {
+		var cur eventCursor
+		if err := decodeCursor(raw, &cur); err != nil || cur.ID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		filter.AfterID = cur.ID
+	}
+
+	// Customers should only see their own shipment
+	shipment, err := h.visibleShipment(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment events"})
+		return
+	}
+	if shipment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return
+	}
+
+	// Fetch one extra event to learn whether another page follows.
+	filter.Limit = limit + 1
+	events, err := h.Events.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment events", "details": err.Error()})
+		return
+	}
+
+	var next string
+	if len(events) > limit {
+		events = events[:limit]
+		next = encodeCursor(eventCursor{ID: events[limit-1].ID})
+	}
+	if events == nil {
+		events = []store.ShipmentEvent{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shipment_id":     shipment.ID,
+		"tracking_number": shipment.TrackingNumber,
+		"events":          events,
+		"next_cursor":     next,
+	})
+}
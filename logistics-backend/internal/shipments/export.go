@@ -0,0 +1,212 @@
+package shipments
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"logistics-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// exportFlushEvery rows, CSV and NDJSON exports are flushed to the client.
+const exportFlushEvery = 500
+
+var exportHeader = []string{
+	"id", "tracking_number", "status", "origin_warehouse_id",
+	"destination_address", "destination_latitude", "destination_longitude",
+	"service_level", "price", "currency", "piece_count",
+	"customer_id", "driver_id", "driver_name", "driver_email",
+	"created_at", "updated_at",
+	"latest_event_status", "latest_event_latitude", "latest_event_longitude", "latest_event_timestamp",
+}
+
+// exportRecord flattens a summary into the columns of exportHeader.
+func exportRecord(s *store.ShipmentSummary) []string {
+	optFloat := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	}
+	optTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	record := []string{
+		strconv.FormatInt(s.ID, 10), s.TrackingNumber, s.Status, strconv.FormatInt(s.OriginWarehouseID, 10),
+		s.DestinationAddress, optFloat(s.DestinationLatitude), optFloat(s.DestinationLongitude),
+		s.ServiceLevel, optFloat(s.Price), s.Currency, strconv.Itoa(s.PieceCount),
+		strconv.FormatInt(s.CustomerID, 10),
+	}
+	driver := []string{"", "", ""}
+	if s.Driver != nil {
+		driver = []string{strconv.FormatInt(s.Driver.ID, 10), s.Driver.Name, s.Driver.Email}
+	}
+	record = append(record, driver...)
+	record = append(record, optTime(&s.CreatedAt), optTime(s.UpdatedAt))
+	event := []string{"", "", "", ""}
+	if e := s.LatestEvent; e != nil {
+		event = []string{e.Status, optFloat(e.Latitude), optFloat(e.Longitude), optTime(&e.Timestamp)}
+	}
+	return append(record, event...)
+}
+
+// formulaPrefixes start the cells spreadsheets evaluate as formulas.
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeFormulas quotes the cells of a CSV record that a spreadsheet would
+// otherwise run as formulas, such as an address typed as
+// =HYPERLINK("http://…"). Numbers, negative coordinates among them, are
+// left alone. It modifies record in place.
+func escapeFormulas(record []string) []string {
+	for i, v := range record {
+		if v == "" || !strings.ContainsRune(formulaPrefixes, rune(v[0])) {
+			continue
+		}
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			continue
+		}
+		record[i] = "'" + v
+	}
+	return record
+}
+
+// exportLine is one NDJSON line.
+type exportLine struct {
+	store.Shipment
+	Driver      *store.User          `json:"driver,omitempty"`
+	LatestEvent *store.ShipmentEvent `json:"latest_event,omitempty"`
+	PieceCount  int                  `json:"piece_count"`
+}
+
+// ExportShipments streams every shipment matching the list filters and sort,
+// with its latest event, driver and number of pieces, as format=csv (the
+// default), xlsx or ndjson. CSV cells are escaped against formulas.
+//
+// CSV and NDJSON rows go to the client as they are read. XLSX is a zip
+// archive that can only be finished once every row is known, so rows are
+// spooled through excelize's stream writer, which keeps large sheets on
+// disk rather than in memory, and sent at the end.
+func (h *Handler) ExportShipments(c *gin.Context) {
+	filter, ok := shipmentFilter(c)
+	if !ok {
+		return
+	}
+	query := store.ShipmentQuery{ShipmentFilter: filter}
+	if !shipmentSort(c, &query) {
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	filename := fmt.Sprintf("shipments-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	ctx := c.Request.Context()
+
+	var err error
+	switch format {
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Status(http.StatusOK)
+
+		w := csv.NewWriter(c.Writer)
+		rows := 0
+		if err = w.Write(exportHeader); err == nil {
+			err = h.Shipments.Each(ctx, query, func(s *store.ShipmentSummary) error {
+				if err := w.Write(escapeFormulas(exportRecord(s))); err != nil {
+					return err
+				}
+				if rows++; rows%exportFlushEvery == 0 {
+					w.Flush()
+					c.Writer.Flush()
+				}
+				return w.Error()
+			})
+		}
+		w.Flush()
+
+	case "ndjson":
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Status(http.StatusOK)
+
+		enc := json.NewEncoder(c.Writer)
+		rows := 0
+		err = h.Shipments.Each(ctx, query, func(s *store.ShipmentSummary) error {
+			if rows++; rows%exportFlushEvery == 0 {
+				c.Writer.Flush()
+			}
+			return enc.Encode(exportLine{Shipment: s.Shipment, Driver: s.Driver, LatestEvent: s.LatestEvent, PieceCount: s.PieceCount})
+		})
+
+	case "xlsx":
+		f := excelize.NewFile()
+		defer f.Close()
+		sheet := f.GetSheetName(0)
+		var sw *excelize.StreamWriter
+		if sw, err = f.NewStreamWriter(sheet); err != nil {
+			break
+		}
+
+		line := 1
+		writeRow := func(values []string) error {
+			row := make([]any, len(values))
+			for i, v := range values {
+				row[i] = v
+			}
+			cell, err := excelize.CoordinatesToCellName(1, line)
+			if err != nil {
+				return err
+			}
+			line++
+			return sw.SetRow(cell, row)
+		}
+		if err = writeRow(exportHeader); err != nil {
+			break
+		}
+		err = h.Shipments.Each(ctx, query, func(s *store.ShipmentSummary) error {
+			return writeRow(exportRecord(s))
+		})
+		if err == nil {
+			err = sw.Flush()
+		}
+		if err != nil {
+			break
+		}
+
+		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Status(http.StatusOK)
+		_, err = f.WriteTo(c.Writer)
+
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv, xlsx or ndjson"})
+		return
+	}
+
+	if err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			c.Writer.Header().Del("Content-Type")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export shipments", "details": err.Error()})
+			return
+		}
+		// The status line is already sent. Dropping the connection before
+		// the final chunk is the only way left to tell the client the file
+		// is incomplete.
+		log.Printf("Export of shipments failed after %d bytes: %v", c.Writer.Size(), err)
+		if conn, _, hijackErr := c.Writer.Hijack(); hijackErr == nil {
+			conn.Close()
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package shipments
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEscapeFormulas(t *testing.T) {
+	record := []string{
+		`=HYPERLINK("http://example.com")`, "+91 98765 43210", "-2+3", "@SUM(A1)", "\tTab", "\rReturn",
+		"-73.9857", "+1.5", "12 MG Road", "", "a=b", "O'Brien",
+	}
+	want := []string{
+		`'=HYPERLINK("http://example.com")`, "'+91 98765 43210", "'-2+3", "'@SUM(A1)", "'\tTab", "'\rReturn",
+		// Numbers stay numbers.
+		"-73.9857", "+1.5",
+		"12 MG Road", "", "a=b", "O'Brien",
+	}
+	if got := escapeFormulas(record); !slices.Equal(got, want) {
+		t.Fatalf("escapeFormulas = %q, want %q", got, want)
+	}
+}
@@ -1,57 +1,183 @@
 package shipments
 
 import (
-	"database/sql"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
-	"logistics-backend/internal/database"
+	"logistics-backend/internal/store"
+	"logistics-backend/internal/tracking"
 
 	"github.com/gin-gonic/gin"
 )
 
-func GetShipments(c *gin.Context) {
-	role := c.GetString("role")
-	userID := c.GetInt("user_id")
+var trackingPrefix = regexp.MustCompile(`^[A-Z0-9-]+$`)
 
-	var rows *sql.Rows
-	var err error
+// idParam parses an optional numeric query parameter.
+func idParam(c *gin.Context, name string) (int64, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
 
-	if role == "customer" {
-		rows, err = database.DB.Query(`
-            SELECT id, tracking_number, origin_warehouse_id, destination_address, customer_id, status
-            FROM shipments
-            WHERE customer_id = ?`, userID)
-	} else {
-		// manager & driver
-		rows, err = database.DB.Query(`
-            SELECT id, tracking_number, origin_warehouse_id, destination_address, customer_id, status
-            FROM shipments`)
+// shipmentFilter reads the listing filters shared by the shipment and
+// coordinate listings: status, origin_warehouse_id, driver_id, customer_id,
+// created_from, created_to, tracking_prefix and needs_manual_pin. Customers
+// are always limited to their own shipments, whatever customer_id says.
+func shipmentFilter(c *gin.Context) (store.ShipmentFilter, bool) {
+	f := store.ShipmentFilter{Statuses: listParam(c, "status")}
+	for _, st := range f.Statuses {
+		if !ValidStatus(st) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status", "details": st})
+			return f, false
+		}
 	}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipments"})
-		return
+	var ok bool
+	if f.OriginWarehouseID, ok = idParam(c, "origin_warehouse_id"); !ok {
+		return f, false
+	}
+	if f.DriverID, ok = idParam(c, "driver_id"); !ok {
+		return f, false
+	}
+	if f.CustomerID, ok = idParam(c, "customer_id"); !ok {
+		return f, false
+	}
+	if f.CreatedFrom, ok = timeParam(c, "created_from"); !ok {
+		return f, false
+	}
+	if f.CreatedTo, ok = timeParam(c, "created_to"); !ok {
+		return f, false
+	}
+	if raw := c.Query("tracking_prefix"); raw != "" {
+		f.TrackingPrefix = tracking.Normalize(raw)
+		if !trackingPrefix.MatchString(f.TrackingPrefix) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tracking_prefix may only hold letters, digits and dashes"})
+			return f, false
+		}
+	}
+	if f.NeedsManualPin, ok = boolParam(c, "needs_manual_pin"); !ok {
+		return f, false
+	}
+
+	// Customers only see their own shipments
+	if c.GetString("role") == "customer" {
+		f.CustomerID = int64(c.GetInt("user_id"))
+	}
+	return f, true
+}
+
+// shipmentCursor is the decoded page token of a shipment listing. It keeps
+// the sort it was issued for, so it cannot be replayed against another.
+type shipmentCursor struct {
+	SortBy         string     `json:"sort"`
+	Desc           bool       `json:"desc,omitempty"`
+	ID             int64      `json:"id"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	TrackingNumber string     `json:"tracking_number,omitempty"`
+	Status         string     `json:"status,omitempty"`
+}
+
+func newShipmentCursor(q store.ShipmentQuery, last store.Shipment) shipmentCursor {
+	cur := shipmentCursor{SortBy: q.SortBy, Desc: q.Desc, ID: last.ID}
+	switch q.SortBy {
+	case store.SortByCreatedAt:
+		cur.CreatedAt = &last.CreatedAt
+	case store.SortByTrackingNumber:
+		cur.TrackingNumber = last.TrackingNumber
+	case store.SortByStatus:
+		cur.Status = last.Status
+	}
+	return cur
+}
+
+func (cur shipmentCursor) shipment() *store.Shipment {
+	sh := &store.Shipment{ID: cur.ID, TrackingNumber: cur.TrackingNumber, Status: cur.Status}
+	if cur.CreatedAt != nil {
+		sh.CreatedAt = *cur.CreatedAt
 	}
-	defer rows.Close()
+	return sh
+}
 
-	type Shipment struct {
-		ID                 int    `json:"id"`
-		TrackingNumber     string `json:"tracking_number"`
-		OriginWarehouseID  int    `json:"origin_warehouse_id"`
-		DestinationAddress string `json:"destination_address"`
-		CustomerID         int    `json:"customer_id"`
-		Status             string `json:"status"`
+// shipmentSort reads the sort query parameter: id, created_at,
+// tracking_number or status, prefixed with "-" for descending order.
+func shipmentSort(c *gin.Context, q *store.ShipmentQuery) bool {
+	sortKey := c.DefaultQuery("sort", store.SortByID)
+	q.SortBy = strings.TrimPrefix(sortKey, "-")
+	q.Desc = q.SortBy != sortKey
+	switch q.SortBy {
+	case store.SortByID, store.SortByCreatedAt, store.SortByTrackingNumber, store.SortByStatus:
+		return true
 	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort", "details": sortKey})
+	return false
+}
 
-	var shipments []Shipment
+// GetShipments lists shipments matching the shipmentFilter parameters,
+// sorted as shipmentSort describes and paged by limit and cursor.
+//
+// The body stays a plain array for existing clients; the total number of
+// matches and the next page's cursor travel in the X-Total-Count and
+// X-Next-Cursor headers. A request with neither limit nor cursor is not
+// paged, since clients written before paging expect every match.
+func (h *Handler) GetShipments(c *gin.Context) {
+	filter, ok := shipmentFilter(c)
+	if !ok {
+		return
+	}
+	query := store.ShipmentQuery{ShipmentFilter: filter}
+	if !shipmentSort(c, &query) {
+		return
+	}
 
-	for rows.Next() {
-		var s Shipment
-		if err := rows.Scan(&s.ID, &s.TrackingNumber, &s.OriginWarehouseID, &s.DestinationAddress, &s.CustomerID, &s.Status); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse shipments"})
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	if raw := c.Query("cursor"); raw != "" {
+		var cur shipmentCursor
+		if err := decodeCursor(raw, &cur); err != nil || cur.ID <= 0 ||
+			cur.SortBy != query.SortBy || cur.Desc != query.Desc ||
+			(cur.SortBy == store.SortByCreatedAt && cur.CreatedAt == nil) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
 			return
 		}
-		shipments = append(shipments, s)
+		query.After = cur.shipment()
+	}
+
+	total, err := h.Shipments.Count(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipments"})
+		return
+	}
+
+	// Fetch one extra shipment to learn whether another page follows.
+	paged := c.Query("limit") != "" || c.Query("cursor") != ""
+	if paged {
+		query.Limit = limit + 1
+	}
+	shipments, err := h.Shipments.List(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipments"})
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	if paged && len(shipments) > limit {
+		shipments = shipments[:limit]
+		c.Header("X-Next-Cursor", encodeCursor(newShipmentCursor(query, shipments[limit-1])))
+	}
+	if shipments == nil {
+		shipments = []store.Shipment{}
 	}
 
 	c.JSON(http.StatusOK, shipments)
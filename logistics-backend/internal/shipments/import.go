@@ -0,0 +1,424 @@
+package shipments
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"logistics-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	maxImportBytes = 10 << 20
+	maxImportRows  = 10000
+	// importBatchSize rows are inserted per transaction, each under a
+	// savepoint of its own.
+	importBatchSize = 100
+)
+
+// Import fields a file's columns can be mapped to. origin_warehouse takes
+// a warehouse ID or code and customer a customer ID or email.
+const (
+	fieldOriginWarehouse      = "origin_warehouse"
+	fieldCustomer             = "customer"
+	fieldDestinationAddress   = "destination_address"
+	fieldDestinationLatitude  = "destination_latitude"
+	fieldDestinationLongitude = "destination_longitude"
+	fieldServiceLevel         = "service_level"
+)
+
+var importFields = []string{
+	fieldOriginWarehouse,
+	fieldCustomer,
+	fieldDestinationAddress,
+	fieldDestinationLatitude,
+	fieldDestinationLongitude,
+	fieldServiceLevel,
+}
+
+// Outcomes of an imported row.
+const (
+	rowCreated = "created"
+	rowValid   = "valid" // passed validation in a dry run
+	rowInvalid = "invalid"
+	rowFailed  = "failed" // valid, but could not be stored
+)
+
+type importRow struct {
+	// Line is the row's 1-based line in the file, counting the header.
+	Line           int      `json:"line"`
+	Status         string   `json:"status"`
+	Errors         []string `json:"errors,omitempty"`
+	ID             int64    `json:"id,omitempty"`
+	TrackingNumber string   `json:"tracking_number,omitempty"`
+	Price          *float64 `json:"price,omitempty"`
+	Currency       string   `json:"currency,omitempty"`
+
+	shipment *store.Shipment
+}
+
+type importReport struct {
+	DryRun  bool        `json:"dry_run"`
+	Total   int         `json:"total"`
+	Valid   int         `json:"valid"`
+	Invalid int         `json:"invalid"`
+	Created int         `json:"created"`
+	Failed  int         `json:"failed"`
+	Rows    []importRow `json:"rows"`
+}
+
+// rowReader yields the records of an uploaded sheet, header first, and
+// io.EOF at the end.
+type rowReader interface {
+	Read() ([]string, error)
+}
+
+type xlsxRows struct {
+	rows *excelize.Rows
+}
+
+func (x xlsxRows) Read() ([]string, error) {
+	if !x.rows.Next() {
+		if err := x.rows.Error(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return x.rows.Columns()
+}
+
+// openSheet picks a reader from the file extension. XLSX files are read
+// from the named sheet, or the first one.
+func openSheet(r io.Reader, filename, sheet string) (rowReader, func(), error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv", "":
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+		return cr, func() {}, nil
+	case ".xlsx":
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if sheet == "" {
+			sheet = f.GetSheetName(0)
+		}
+		rows, err := f.Rows(sheet)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return xlsxRows{rows}, func() { rows.Close(); f.Close() }, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported file type %q, expected .csv or .xlsx", filepath.Ext(filename))
+}
+
+// columnIndexes resolves the mapping of import fields to header names into
+// column positions. Fields missing from mapping are looked up by their own
+// name; headers compare case-insensitively.
+func columnIndexes(header []string, mapping map[string]string) (map[string]int, error) {
+	for field := range mapping {
+		if !slices.Contains(importFields, field) {
+			return nil, fmt.Errorf("unknown field %q in mapping", field)
+		}
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, h := range header {
+		positions[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+
+	columns := make(map[string]int)
+	for _, field := range importFields {
+		name, mapped := mapping[field]
+		if !mapped {
+			name = field
+		}
+		i, ok := positions[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			if mapped {
+				return nil, fmt.Errorf("column %q mapped to %s is not in the header", name, field)
+			}
+			continue
+		}
+		columns[field] = i
+	}
+	for _, field := range []string{fieldOriginWarehouse, fieldCustomer, fieldDestinationAddress} {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("no column for required field %s", field)
+		}
+	}
+	return columns, nil
+}
+
+// importLookups resolves warehouse and customer references without a query
+// per row.
+type importLookups struct {
+	warehouses map[string]*store.Warehouse // by ID and upper-case code
+	customers  map[string]int64            // by ID and lower-case email
+}
+
+func (h *Handler) loadImportLookups(ctx context.Context) (*importLookups, error) {
+	l := &importLookups{warehouses: make(map[string]*store.Warehouse), customers: make(map[string]int64)}
+
+	warehouses, err := h.Warehouses.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range warehouses {
+		w := &warehouses[i]
+		l.warehouses[strconv.FormatInt(w.ID, 10)] = w
+		if w.Code != "" {
+			l.warehouses[strings.ToUpper(w.Code)] = w
+		}
+	}
+
+	customers, err := h.Users.List(ctx, "customer")
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range customers {
+		l.customers[strconv.FormatInt(u.ID, 10)] = u.ID
+		l.customers[strings.ToLower(u.Email)] = u.ID
+	}
+	return l, nil
+}
+
+// parseImportRow validates one record and builds its shipment. A row that
+// is otherwise valid is geocoded and priced as Create would, so that a dry
+// run catches the rows Create would refuse.
+func (s *Service) parseImportRow(ctx context.Context, l *importLookups, record []string, columns map[string]int) (*store.Shipment, []string) {
+	cell := func(field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var errs []string
+	sh := &store.Shipment{}
+
+	ref := cell(fieldOriginWarehouse)
+	warehouse, ok := l.warehouses[strings.ToUpper(ref)]
+	if ref == "" {
+		errs = append(errs, "origin_warehouse is required")
+	} else if ok {
+		sh.OriginWarehouseID = warehouse.ID
+	} else {
+		errs = append(errs, fmt.Sprintf("origin_warehouse %q does not exist", ref))
+	}
+
+	if ref := cell(fieldCustomer); ref == "" {
+		errs = append(errs, "customer is required")
+	} else if id, ok := l.customers[strings.ToLower(ref)]; ok {
+		sh.CustomerID = id
+	} else {
+		errs = append(errs, fmt.Sprintf("customer %q does not exist", ref))
+	}
+
+	sh.DestinationAddress = cell(fieldDestinationAddress)
+	if sh.DestinationAddress == "" {
+		errs = append(errs, "destination_address is required")
+	}
+
+	latRaw, lngRaw := cell(fieldDestinationLatitude), cell(fieldDestinationLongitude)
+	switch {
+	case latRaw == "" && lngRaw == "":
+	case latRaw == "" || lngRaw == "":
+		errs = append(errs, "destination_latitude and destination_longitude must be given together")
+	default:
+		lat, latErr := strconv.ParseFloat(latRaw, 64)
+		lng, lngErr := strconv.ParseFloat(lngRaw, 64)
+		if latErr != nil || lat < -90 || lat > 90 {
+			errs = append(errs, fmt.Sprintf("destination_latitude %q is not between -90 and 90", latRaw))
+		}
+		if lngErr != nil || lng < -180 || lng > 180 {
+			errs = append(errs, fmt.Sprintf("destination_longitude %q is not between -180 and 180", lngRaw))
+		}
+		sh.DestinationLatitude, sh.DestinationLongitude = &lat, &lng
+	}
+
+	sh.ServiceLevel = cell(fieldServiceLevel)
+	if err := s.checkServiceLevel(sh.ServiceLevel); err != nil {
+		errs = append(errs, fmt.Sprintf("service_level %q is not one of %s", sh.ServiceLevel, strings.Join(s.opts.Rates.ServiceLevels, ", ")))
+	}
+
+	if len(errs) > 0 {
+		return sh, errs
+	}
+	s.locate(ctx, sh)
+	if err := s.price(sh, warehouse, []store.Piece{{}}); err != nil {
+		errs = append(errs, err.Error())
+	}
+	return sh, errs
+}
+
+// ImportShipments creates shipments from an uploaded CSV or XLSX file.
+//
+// The multipart form carries the file as "file", an optional JSON object
+// "mapping" from import field to column header and, for XLSX, an optional
+// "sheet". Every row is validated and priced first; with dry_run=true
+// nothing is stored. Valid rows are inserted in batches of importBatchSize,
+// each batch in its own transaction, and the response reports the outcome
+// and price of every row.
+func (h *Handler) ImportShipments(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file upload named \"file\" is required", "details": err.Error()})
+		return
+	}
+
+	var mapping map[string]string
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mapping must be a JSON object of field to column", "details": err.Error()})
+			return
+		}
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload", "details": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	rows, closeRows, err := openSheet(file, fileHeader.Filename, c.PostForm("sheet"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to open file", "details": err.Error()})
+		return
+	}
+	defer closeRows()
+
+	header, err := rows.Read()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read header row", "details": err.Error()})
+		return
+	}
+	columns, err := columnIndexes(header, mapping)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid column mapping", "details": err.Error()})
+		return
+	}
+
+	lookups, err := h.loadImportLookups(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import shipments", "details": err.Error()})
+		return
+	}
+
+	report := importReport{DryRun: dryRun, Rows: []importRow{}}
+	for line := 2; ; line++ {
+		record, err := rows.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to read line %d", line), "details": err.Error()})
+			return
+		}
+		if blank(record) {
+			continue
+		}
+		if report.Total == maxImportRows {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Imports are limited to %d rows", maxImportRows)})
+			return
+		}
+		report.Total++
+
+		sh, errs := h.Service.parseImportRow(c.Request.Context(), lookups, record, columns)
+		row := importRow{Line: line, Status: rowValid, Errors: errs, shipment: sh}
+		if len(errs) > 0 {
+			row.Status = rowInvalid
+			report.Invalid++
+		} else {
+			row.Price, row.Currency = sh.Price, sh.Currency
+			report.Valid++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	if !dryRun {
+		h.Service.createBatches(c.Request.Context(), report.Rows)
+		for _, row := range report.Rows {
+			switch row.Status {
+			case rowCreated:
+				report.Created++
+			case rowFailed:
+				report.Failed++
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func blank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// createBatches stores the valid rows importBatchSize at a time. Every row
+// is created under a savepoint, so a row that fails is rolled back and
+// marked failed alone. A batch that fails to commit marks all its rows
+// failed.
+func (s *Service) createBatches(ctx context.Context, rows []importRow) {
+	var batch []*importRow
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		rowErrs := make(map[*importRow]error)
+		err := s.stores.Tx.WithinTx(ctx, func(tx *store.Stores) error {
+			txService := s.withStores(tx)
+			for _, row := range batch {
+				if _, err := txService.Create(ctx, row.shipment, nil); err != nil {
+					rowErrs[row] = err
+				}
+			}
+			return nil
+		})
+		for _, row := range batch {
+			if err := cmp.Or(err, rowErrs[row]); err != nil {
+				row.Status = rowFailed
+				row.Errors = append(row.Errors, err.Error())
+				continue
+			}
+			row.Status = rowCreated
+			row.ID = row.shipment.ID
+			row.TrackingNumber = row.shipment.TrackingNumber
+			row.Price, row.Currency = row.shipment.Price, row.shipment.Currency
+		}
+		batch = batch[:0]
+	}
+
+	for i := range rows {
+		if rows[i].Status != rowValid {
+			continue
+		}
+		batch = append(batch, &rows[i])
+		if len(batch) == importBatchSize {
+			flush()
+		}
+	}
+	flush()
+}
@@ -0,0 +1,94 @@
+package shipments
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"logistics-backend/internal/config"
+	"logistics-backend/internal/database"
+	"logistics-backend/internal/migrations"
+	"logistics-backend/internal/store"
+	"logistics-backend/internal/store/memory"
+	"logistics-backend/internal/store/sqlstore"
+	"logistics-backend/internal/tracking"
+)
+
+func openSQLite(t *testing.T) *store.Stores {
+	db, dialect, err := database.Open(&config.Config{DBDriver: "sqlite", DBPath: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	m, err := migrations.New(db, dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return sqlstore.New(db, dialect)
+}
+
+// TestCreateBatchesPartialFailure checks that a row failing inside a batch
+// is reported alone, while the rows around it are created.
+func TestCreateBatchesPartialFailure(t *testing.T) {
+	for name, open := range map[string]func(*testing.T) *store.Stores{
+		"memory": func(*testing.T) *store.Stores { return memory.New() },
+		"sqlite": openSQLite,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			stores := open(t)
+			customer := store.User{Name: "Customer", Email: "customer@example.com", PasswordHash: "x", Role: "customer"}
+			if err := stores.Users.Create(ctx, &customer); err != nil {
+				t.Fatal(err)
+			}
+			warehouse := store.Warehouse{Name: "Delhi Hub", Code: "DEL", Latitude: 28.6139, Longitude: 77.2090}
+			if err := stores.Warehouses.Create(ctx, &warehouse); err != nil {
+				t.Fatal(err)
+			}
+			format, err := tracking.ParseFormat("LG{warehouse}{seq:8}{check}", tracking.Mod11)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := NewService(stores, tracking.NewGenerator(format, stores.Sequences), Options{})
+
+			row := func(line int, warehouseID int64) importRow {
+				return importRow{Line: line, Status: rowValid, shipment: &store.Shipment{
+					OriginWarehouseID:  warehouseID,
+					DestinationAddress: "12 MG Road, Bengaluru",
+					CustomerID:         customer.ID,
+				}}
+			}
+			rows := []importRow{
+				row(2, warehouse.ID),
+				// The warehouse was deleted after the rows were validated.
+				row(3, warehouse.ID+100),
+				{Line: 4, Status: rowInvalid, Errors: []string{"customer: unknown"}},
+				row(5, warehouse.ID),
+			}
+			s.createBatches(ctx, rows)
+
+			want := []string{rowCreated, rowFailed, rowInvalid, rowCreated}
+			for i, r := range rows {
+				if r.Status != want[i] {
+					t.Fatalf("line %d %s, want %s", r.Line, r.Status, want[i])
+				}
+			}
+			if len(rows[1].Errors) != 1 || rows[1].ID != 0 || rows[1].TrackingNumber != "" {
+				t.Fatalf("failed row reported as %+v", rows[1])
+			}
+			if len(rows[2].Errors) != 1 {
+				t.Fatalf("invalid row reported as %+v", rows[2])
+			}
+			// Numbering carries on across the failed row.
+			if rows[0].TrackingNumber != format.Number("DEL", 1) || rows[3].TrackingNumber != format.Number("DEL", 2) {
+				t.Fatalf("created %s and %s", rows[0].TrackingNumber, rows[3].TrackingNumber)
+			}
+			if n, err := stores.Shipments.Count(ctx, store.ShipmentFilter{}); err != nil || n != 2 {
+				t.Fatalf("%d shipments stored, %v", n, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package shipments
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"logistics-backend/internal/label"
+	"logistics-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBulkLabels bounds the shipments one bulk label request may print.
+const maxBulkLabels = 200
+
+// labelFormat reads the format query parameter, pdf by default.
+func labelFormat(c *gin.Context) (string, bool) {
+	format := c.DefaultQuery("format", label.FormatPDF)
+	if !slices.Contains(label.Formats, format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of " + strings.Join(label.Formats, ", ")})
+		return "", false
+	}
+	return format, true
+}
+
+// shipmentLabels collects the labels of the shipments with the given IDs,
+// in order. Shipments that do not exist or the caller may not see are
+// returned as missing instead.
+func (h *Handler) shipmentLabels(c *gin.Context, ids []int64) ([]label.Label, []int64, error) {
+	ctx := c.Request.Context()
+	warehouses := make(map[int64]*store.Warehouse)
+	var labels []label.Label
+	var missing []int64
+	for _, id := range ids {
+		shipment, err := h.visibleShipment(c, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if shipment == nil {
+			missing = append(missing, id)
+			continue
+		}
+		l, err := h.shipmentLabel(ctx, shipment, warehouses)
+		if err != nil {
+			return nil, nil, err
+		}
+		labels = append(labels, l)
+	}
+	return labels, missing, nil
+}
+
+// shipmentLabel builds the label of a shipment, looking its origin up in
+// warehouses before asking the store.
+func (h *Handler) shipmentLabel(ctx context.Context, sh *store.Shipment, warehouses map[int64]*store.Warehouse) (label.Label, error) {
+	w, ok := warehouses[sh.OriginWarehouseID]
+	if !ok {
+		var err error
+		if w, err = h.Warehouses.GetByID(ctx, sh.OriginWarehouseID); err != nil {
+			return label.Label{}, err
+		}
+		warehouses[sh.OriginWarehouseID] = w
+	}
+	pieces, err := h.Pieces.ListByShipment(ctx, sh.ID)
+	if err != nil {
+		return label.Label{}, err
+	}
+	l := label.Label{
+		TrackingNumber: sh.TrackingNumber,
+		Origin:         w.Name,
+		OriginCode:     w.Code,
+		Destination:    sh.DestinationAddress,
+		Pieces:         len(pieces),
+	}
+	if sh.PickupAddress != "" {
+		l.Origin, l.OriginCode = sh.PickupAddress, ""
+	}
+	return l, nil
+}
+
+// sendLabels renders labels into memory first, so that a rendering error
+// can still be answered with a JSON error instead of a truncated file.
+func sendLabels(c *gin.Context, format, name string, labels []label.Label) {
+	var buf bytes.Buffer
+	if err := label.Write(&buf, format, labels); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render labels", "details": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+name+"."+format+`"`)
+	c.Data(http.StatusOK, label.ContentType(format), buf.Bytes())
+}
+
+// GetShipmentLabel renders the shipping label of a shipment, as a PDF or,
+// with format=zpl, for a thermal printer.
+func (h *Handler) GetShipmentLabel(c *gin.Context) {
+	id, ok := shipmentID(c)
+	if !ok {
+		return
+	}
+	format, ok := labelFormat(c)
+	if !ok {
+		return
+	}
+
+	labels, missing, err := h.shipmentLabels(c, []int64{id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment", "details": err.Error()})
+		return
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return
+	}
+	sendLabels(c, format, "label-"+labels[0].TrackingNumber, labels)
+}
+
+// GetShipmentLabels renders the labels of several shipments into one
+// document, in the order of the ids parameter. If any of the shipments is
+// not found, nothing is rendered and the missing IDs are reported.
+func (h *Handler) GetShipmentLabels(c *gin.Context) {
+	format, ok := labelFormat(c)
+	if !ok {
+		return
+	}
+
+	var ids []int64
+	for _, raw := range listParam(c, "ids") {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid shipment ID %q", raw)})
+			return
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids is required"})
+		return
+	}
+	if len(ids) > maxBulkLabels {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d labels can be printed at once", maxBulkLabels)})
+		return
+	}
+
+	labels, missing, err := h.shipmentLabels(c, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipments", "details": err.Error()})
+		return
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipments not found", "ids": missing})
+		return
+	}
+	sendLabels(c, format, "labels-"+time.Now().UTC().Format("20060102-150405"), labels)
+}
@@ -0,0 +1,322 @@
+package shipments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"logistics-backend/internal/store"
+	"logistics-backend/internal/tracking"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPieces bounds the pieces of one shipment, so piece numbers fit the
+// three digits of a barcode.
+const maxPieces = 999
+
+// PieceBarcode is the barcode printed on piece n of a shipment.
+func PieceBarcode(trackingNumber string, n int) string {
+	return fmt.Sprintf("%s-%03d", trackingNumber, n)
+}
+
+// validatePieces checks the physical attributes entered for new pieces.
+func validatePieces(pieces []store.Piece) error {
+	if len(pieces) > maxPieces {
+		return &ValidationError{Fields: map[string]string{"pieces": fmt.Sprintf("at most %d pieces are allowed", maxPieces)}}
+	}
+
+	invalid := make(map[string]string)
+	for i, p := range pieces {
+		field := func(name string) string { return fmt.Sprintf("pieces[%d].%s", i, name) }
+		for name, v := range map[string]*float64{
+			"weight_kg": p.WeightKg,
+			"length_cm": p.LengthCm,
+			"width_cm":  p.WidthCm,
+			"height_cm": p.HeightCm,
+		} {
+			if v != nil && *v <= 0 {
+				invalid[field(name)] = "must be positive"
+			}
+		}
+		if p.DeclaredValue != nil && *p.DeclaredValue < 0 {
+			invalid[field("declared_value")] = "must not be negative"
+		}
+		if len(p.Description) > 255 {
+			invalid[field("description")] = "must be at most 255 characters"
+		}
+	}
+	if len(invalid) > 0 {
+		return &ValidationError{Fields: invalid}
+	}
+	return nil
+}
+
+// rollUp derives a shipment's status from its pieces: cancelled once every
+// piece is, delivered once every piece still travelling has arrived, in
+// transit while any piece is on its way, failed while none is but some are
+// waiting for a reattempt, in transit once any has arrived, and pending
+// before that.
+func rollUp(pieces []store.Piece) string {
+	counts := make(map[string]int)
+	for _, p := range pieces {
+		counts[p.Status]++
+	}
+	switch n := len(pieces); {
+	case counts[StatusCancelled] == n:
+		return StatusCancelled
+	case counts[StatusDelivered]+counts[StatusCancelled] == n:
+		return StatusDelivered
+	case counts[StatusInTransit] > 0:
+		return StatusInTransit
+	case counts[StatusFailed] > 0:
+		return StatusFailed
+	case counts[StatusDelivered] > 0:
+		return StatusInTransit
+	}
+	return StatusPending
+}
+
+// ChangePieceStatus moves the piece with the given barcode to t.To and
+// records the event against the piece. When the piece changes the status
+// its shipment rolls up to, the shipment follows with an event of its own.
+// Failed attempts and returns concern whole shipments, so their statuses
+// count as unknown here. It fails like ChangeStatus; t.ShipmentID is
+// ignored.
+func (s *Service) ChangePieceStatus(ctx context.Context, barcode string, t Transition) (*store.ShipmentEvent, error) {
+	if !ValidStatus(t.To) || shipmentOnly(t.To) {
+		return nil, ErrUnknownStatus
+	}
+
+	var event *store.ShipmentEvent
+	err := s.stores.Tx.WithinTx(ctx, func(tx *store.Stores) error {
+		piece, err := tx.Pieces.GetByBarcode(ctx, barcode)
+		if err != nil {
+			return err
+		}
+		shipment, err := tx.Shipments.GetByID(ctx, piece.ShipmentID)
+		if err != nil {
+			return err
+		}
+		if t.ActorRole == "driver" && (shipment.DriverID == nil || *shipment.DriverID != t.ActorID) {
+			return ErrNotAssigned
+		}
+		if !CanTransition(piece.Status, t.To) {
+			return &TransitionError{From: piece.Status, To: t.To}
+		}
+
+		if err := tx.Pieces.UpdateStatus(ctx, piece.ID, piece.Status, t.To); err != nil {
+			return err
+		}
+		actorID := t.ActorID
+		event = &store.ShipmentEvent{
+			ShipmentID: shipment.ID,
+			PieceID:    &piece.ID,
+			Status:     t.To,
+			Latitude:   t.Latitude,
+			Longitude:  t.Longitude,
+			ActorID:    &actorID,
+			ActorRole:  t.ActorRole,
+			Reason:     t.Reason,
+			ScanType:   t.ScanType,
+			ScannedAt:  t.ScannedAt,
+		}
+		if err := tx.Events.Create(ctx, event); err != nil {
+			return err
+		}
+
+		pieces, err := tx.Pieces.ListByShipment(ctx, shipment.ID)
+		if err != nil {
+			return err
+		}
+		status := rollUp(pieces)
+		if status == shipment.Status {
+			return nil
+		}
+		if !CanTransition(shipment.Status, status) {
+			return &TransitionError{From: shipment.Status, To: status}
+		}
+		if err := tx.Shipments.UpdateStatus(ctx, shipment.ID, shipment.Status, status); err != nil {
+			return err
+		}
+		return tx.Events.Create(ctx, &store.ShipmentEvent{
+			ShipmentID: shipment.ID,
+			Status:     status,
+			Latitude:   t.Latitude,
+			Longitude:  t.Longitude,
+			ActorID:    &actorID,
+			ActorRole:  t.ActorRole,
+			Reason:     t.Reason,
+			ScanType:   t.ScanType,
+			ScannedAt:  t.ScannedAt,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// pieceInput is a piece as entered when creating a shipment.
+type pieceInput struct {
+	Description   string   `json:"description"`
+	WeightKg      *float64 `json:"weight_kg"`
+	LengthCm      *float64 `json:"length_cm"`
+	WidthCm       *float64 `json:"width_cm"`
+	HeightCm      *float64 `json:"height_cm"`
+	DeclaredValue *float64 `json:"declared_value"`
+}
+
+func (in pieceInput) piece() store.Piece {
+	return store.Piece{
+		Description:   strings.TrimSpace(in.Description),
+		WeightKg:      in.WeightKg,
+		LengthCm:      in.LengthCm,
+		WidthCm:       in.WidthCm,
+		HeightCm:      in.HeightCm,
+		DeclaredValue: in.DeclaredValue,
+	}
+}
+
+// GetShipmentPieces lists the pieces of a shipment.
+func (h *Handler) GetShipmentPieces(c *gin.Context) {
+	id, ok := shipmentID(c)
+	if !ok {
+		return
+	}
+
+	// Customers should only see their own shipment
+	shipment, err := h.visibleShipment(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pieces"})
+		return
+	}
+	if shipment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return
+	}
+
+	pieces, err := h.Pieces.ListByShipment(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pieces", "details": err.Error()})
+		return
+	}
+	if pieces == nil {
+		pieces = []store.Piece{}
+	}
+	c.JSON(http.StatusOK, pieces)
+}
+
+// GetPiece looks a piece up by its barcode and returns it with its own
+// timeline.
+func (h *Handler) GetPiece(c *gin.Context) {
+	piece, err := h.Pieces.GetByBarcode(c.Request.Context(), tracking.Normalize(c.Param("barcode")))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Piece not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch piece"})
+		return
+	}
+
+	// Customers should only see their own pieces
+	shipment, err := h.visibleShipment(c, piece.ShipmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch piece"})
+		return
+	}
+	if shipment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Piece not found"})
+		return
+	}
+
+	events, err := h.Events.List(c.Request.Context(), store.EventFilter{ShipmentID: shipment.ID, PieceID: piece.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch piece", "details": err.Error()})
+		return
+	}
+	if events == nil {
+		events = []store.ShipmentEvent{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"piece":           piece,
+		"tracking_number": shipment.TrackingNumber,
+		"shipment_status": shipment.Status,
+		"events":          events,
+	})
+}
+
+// UpdatePieceStatus changes the status of a single scanned piece. It takes
+// the same body as UpdateShipmentStatus.
+func (h *Handler) UpdatePieceStatus(c *gin.Context) {
+	var body struct {
+		Status    string   `json:"status"`
+		Reason    string   `json:"reason"`
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !ValidStatus(body.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+	if shipmentOnly(body.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status " + body.Status + " applies to whole shipments only"})
+		return
+	}
+	if (body.Latitude == nil) != (body.Longitude == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "latitude and longitude must be given together"})
+		return
+	}
+
+	barcode := tracking.Normalize(c.Param("barcode"))
+	_, err := h.Service.ChangePieceStatus(c.Request.Context(), barcode, Transition{
+		To:        body.Status,
+		ActorID:   int64(c.GetInt("user_id")),
+		ActorRole: c.GetString("role"),
+		Reason:    body.Reason,
+		Latitude:  body.Latitude,
+		Longitude: body.Longitude,
+	})
+	var illegal *TransitionError
+	switch {
+	case err == nil:
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Piece not found"})
+		return
+	case errors.Is(err, ErrNotAssigned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own shipments"})
+		return
+	case errors.As(err, &illegal):
+		c.JSON(http.StatusConflict, gin.H{"error": illegal.Error(), "from": illegal.From, "to": illegal.To})
+		return
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Piece status changed concurrently, please retry"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status", "details": err.Error()})
+		return
+	}
+
+	piece, err := h.Pieces.GetByBarcode(c.Request.Context(), barcode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch piece", "details": err.Error()})
+		return
+	}
+	shipment, err := h.Shipments.GetByID(c.Request.Context(), piece.ShipmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Status updated successfully",
+		"piece_status":    piece.Status,
+		"shipment_status": shipment.Status,
+	})
+}
@@ -0,0 +1,46 @@
+package shipments
+
+import (
+	"strings"
+	"testing"
+
+	"logistics-backend/internal/store"
+)
+
+func TestRollUp(t *testing.T) {
+	tests := []struct {
+		pieces string
+		want   string
+	}{
+		// A single piece is the shipment.
+		{"pending", StatusPending},
+		{"in_transit", StatusInTransit},
+		{"delivered", StatusDelivered},
+		{"failed", StatusFailed},
+		{"cancelled", StatusCancelled},
+
+		{"cancelled cancelled cancelled", StatusCancelled},
+		{"delivered cancelled", StatusDelivered},
+		{"delivered delivered", StatusDelivered},
+		{"pending pending", StatusPending},
+		{"pending cancelled", StatusPending},
+		{"pending in_transit", StatusInTransit},
+		{"pending delivered", StatusInTransit},
+		{"in_transit delivered", StatusInTransit},
+		// The piece still to deliver waits for its reattempt.
+		{"delivered failed", StatusFailed},
+		{"delivered failed cancelled", StatusFailed},
+		{"failed cancelled", StatusFailed},
+		// A reattempt under way puts the shipment back in transit.
+		{"in_transit failed delivered", StatusInTransit},
+	}
+	for _, tt := range tests {
+		var pieces []store.Piece
+		for _, status := range strings.Fields(tt.pieces) {
+			pieces = append(pieces, store.Piece{Status: status})
+		}
+		if got := rollUp(pieces); got != tt.want {
+			t.Errorf("rollUp(%s) = %s, want %s", tt.pieces, got, tt.want)
+		}
+	}
+}
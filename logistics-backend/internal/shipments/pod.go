@@ -0,0 +1,356 @@
+package shipments
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"logistics-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxProofImageBytes bounds each uploaded image, maxProofBytes the
+	// whole form.
+	maxProofImageBytes = 5 << 20
+	maxProofBytes      = 2*maxProofImageBytes + 1<<20
+)
+
+// proofImageTypes maps the accepted image types to file extensions. The
+// type is sniffed from the content, not taken from the client.
+var proofImageTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/webp": ".webp",
+}
+
+var (
+	ErrUnsupportedImage = errors.New("image must be PNG, JPEG or WebP")
+	ErrProofExists      = errors.New("shipment already has a proof of delivery")
+)
+
+// Image is an uploaded picture and its sniffed content type.
+type Image struct {
+	Data        []byte
+	ContentType string
+}
+
+// newImage checks that data is one of proofImageTypes.
+func newImage(data []byte) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := proofImageTypes[contentType]; !ok {
+		return nil, ErrUnsupportedImage
+	}
+	return &Image{Data: data, ContentType: contentType}, nil
+}
+
+// Proof is a proof of delivery as submitted by a driver.
+type Proof struct {
+	RecipientName string
+	Signature     *Image
+	// Photo is optional.
+	Photo *Image
+}
+
+// Deliver marks a shipment delivered and attaches the proof to the
+// delivered event. A shipment that was already delivered, by a scan or by
+// its last piece, gets the proof attached to the delivered event it has,
+// unless it has a proof already, which fails with ErrProofExists. t must
+// carry the GPS point of the handover. The images are stored first and
+// removed again if the delivery is rejected; Deliver otherwise fails like
+// ChangeStatus.
+func (s *Service) Deliver(ctx context.Context, t Transition, proof Proof) (*store.DeliveryProof, error) {
+	if t.Latitude == nil || t.Longitude == nil {
+		return nil, errors.New("proof of delivery needs a GPS point")
+	}
+	t.To = StatusDelivered
+
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("pod/%d/%s", t.ShipmentID, hex.EncodeToString(token))
+
+	record := &store.DeliveryProof{
+		ShipmentID:    t.ShipmentID,
+		RecipientName: proof.RecipientName,
+		Latitude:      *t.Latitude,
+		Longitude:     *t.Longitude,
+	}
+	var stored []string
+	put := func(name string, img *Image) (string, error) {
+		key := prefix + "-" + name + proofImageTypes[img.ContentType]
+		if err := s.opts.Blobs.Put(ctx, key, bytes.NewReader(img.Data)); err != nil {
+			return "", err
+		}
+		stored = append(stored, key)
+		return key, nil
+	}
+	cleanup := func() {
+		for _, key := range stored {
+			// The request may have been cancelled; the files must go anyway.
+			if err := s.opts.Blobs.Delete(context.WithoutCancel(ctx), key); err != nil {
+				log.Printf("Removing proof of delivery file %s failed: %v", key, err)
+			}
+		}
+	}
+
+	var err error
+	if record.SignatureKey, err = put("signature", proof.Signature); err != nil {
+		cleanup()
+		return nil, err
+	}
+	record.SignatureType = proof.Signature.ContentType
+	if proof.Photo != nil {
+		if record.PhotoKey, err = put("photo", proof.Photo); err != nil {
+			cleanup()
+			return nil, err
+		}
+		record.PhotoType = proof.Photo.ContentType
+	}
+
+	err = s.stores.Tx.WithinTx(ctx, func(tx *store.Stores) error {
+		event, err := s.withStores(tx).deliveredEvent(ctx, t)
+		if err != nil {
+			return err
+		}
+		record.EventID = event.ID
+		if err := tx.Proofs.Create(ctx, record); err != nil {
+			return err
+		}
+		record, err = tx.Proofs.GetByShipment(ctx, t.ShipmentID)
+		return err
+	})
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	return record, nil
+}
+
+// deliveredEvent delivers the shipment of t and returns the event, or
+// finds the delivered event of a shipment that was delivered without a
+// proof.
+func (s *Service) deliveredEvent(ctx context.Context, t Transition) (*store.ShipmentEvent, error) {
+	shipment, err := s.stores.Shipments.GetByID(ctx, t.ShipmentID)
+	if err != nil {
+		return nil, err
+	}
+	if shipment.Status != StatusDelivered {
+		return s.ChangeStatus(ctx, t)
+	}
+	if t.ActorRole == "driver" && (shipment.DriverID == nil || *shipment.DriverID != t.ActorID) {
+		return nil, ErrNotAssigned
+	}
+	if _, err := s.stores.Proofs.GetByShipment(ctx, shipment.ID); err == nil {
+		return nil, ErrProofExists
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	events, err := s.stores.Events.List(ctx, store.EventFilter{ShipmentID: shipment.ID, Statuses: []string{StatusDelivered}})
+	if err != nil {
+		return nil, err
+	}
+	// Events come oldest first; piece events are left to their pieces.
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].PieceID == nil {
+			return &events[i], nil
+		}
+	}
+	return nil, fmt.Errorf("delivered shipment %d has no delivered event", shipment.ID)
+}
+
+// formImage reads an optional image field of a multipart form.
+func formImage(c *gin.Context, field string) (*Image, error) {
+	fileHeader, err := c.FormFile(field)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if fileHeader.Size > maxProofImageBytes {
+		return nil, fmt.Errorf("%s is larger than %d MB", field, maxProofImageBytes>>20)
+	}
+	f, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxProofImageBytes))
+	if err != nil {
+		return nil, err
+	}
+	img, err := newImage(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", field, err)
+	}
+	return img, nil
+}
+
+// SubmitProofOfDelivery delivers a shipment with a proof of delivery, or
+// attaches the proof to a shipment already delivered without one. The
+// multipart form carries a "signature" image, an optional "photo", the
+// "recipient_name" and the "latitude" and "longitude" of the handover.
+func (h *Handler) SubmitProofOfDelivery(c *gin.Context) {
+	id, ok := shipmentID(c)
+	if !ok {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxProofBytes)
+
+	invalid := make(map[string]string)
+	recipient := strings.TrimSpace(c.PostForm("recipient_name"))
+	if recipient == "" {
+		invalid["recipient_name"] = "is required"
+	} else if len(recipient) > 255 {
+		invalid["recipient_name"] = "must be at most 255 characters"
+	}
+	coordinate := func(field string, limit float64) *float64 {
+		v, err := strconv.ParseFloat(c.PostForm(field), 64)
+		if err != nil || v < -limit || v > limit {
+			invalid[field] = fmt.Sprintf("must be a number between %g and %g", -limit, limit)
+			return nil
+		}
+		return &v
+	}
+	lat, lng := coordinate("latitude", 90), coordinate("longitude", 180)
+
+	signature, err := formImage(c, "signature")
+	if err != nil {
+		invalid["signature"] = err.Error()
+	} else if signature == nil {
+		invalid["signature"] = "is required"
+	}
+	photo, err := formImage(c, "photo")
+	if err != nil {
+		invalid["photo"] = err.Error()
+	}
+	if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proof of delivery", "fields": invalid})
+		return
+	}
+
+	proof, err := h.Service.Deliver(c.Request.Context(), Transition{
+		ShipmentID: id,
+		ActorID:    int64(c.GetInt("user_id")),
+		ActorRole:  c.GetString("role"),
+		Latitude:   lat,
+		Longitude:  lng,
+	}, Proof{RecipientName: recipient, Signature: signature, Photo: photo})
+	var illegal *TransitionError
+	switch {
+	case err == nil:
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return
+	case errors.Is(err, ErrNotAssigned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own shipments"})
+		return
+	case errors.Is(err, ErrProofExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Shipment already has a proof of delivery"})
+		return
+	case errors.As(err, &illegal):
+		c.JSON(http.StatusConflict, gin.H{"error": illegal.Error(), "from": illegal.From, "to": illegal.To})
+		return
+	case errors.Is(err, store.ErrConflict), errors.Is(err, store.ErrDuplicate):
+		c.JSON(http.StatusConflict, gin.H{"error": "Shipment status changed concurrently, please retry"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record proof of delivery", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Shipment delivered", "proof": proof})
+}
+
+// visibleProof loads the proof of a shipment the caller may see.
+func (h *Handler) visibleProof(c *gin.Context) (*store.Shipment, *store.DeliveryProof, bool) {
+	id, ok := shipmentID(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	// Customers should only see their own shipment
+	shipment, err := h.visibleShipment(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch proof of delivery"})
+		return nil, nil, false
+	}
+	if shipment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return nil, nil, false
+	}
+
+	proof, err := h.Proofs.GetByShipment(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment has no proof of delivery"})
+		return nil, nil, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch proof of delivery", "details": err.Error()})
+		return nil, nil, false
+	}
+	return shipment, proof, true
+}
+
+// GetProofOfDelivery returns the details of a shipment's proof of delivery
+// and where to download its images.
+func (h *Handler) GetProofOfDelivery(c *gin.Context) {
+	shipment, proof, ok := h.visibleProof(c)
+	if !ok {
+		return
+	}
+
+	base := fmt.Sprintf("/api/shipments/%d/proof-of-delivery/", shipment.ID)
+	links := gin.H{"signature": base + "signature"}
+	if proof.PhotoKey != "" {
+		links["photo"] = base + "photo"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"tracking_number": shipment.TrackingNumber,
+		"proof":           proof,
+		"links":           links,
+	})
+}
+
+// DownloadProofImage streams the :image ("signature" or "photo") of a
+// shipment's proof of delivery.
+func (h *Handler) DownloadProofImage(c *gin.Context) {
+	shipment, proof, ok := h.visibleProof(c)
+	if !ok {
+		return
+	}
+
+	var key, contentType string
+	switch c.Param("image") {
+	case "signature":
+		key, contentType = proof.SignatureKey, proof.SignatureType
+	case "photo":
+		key, contentType = proof.PhotoKey, proof.PhotoType
+	}
+	if key == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	r, err := h.Service.opts.Blobs.Open(c.Request.Context(), key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read image", "details": err.Error()})
+		return
+	}
+	defer r.Close()
+
+	filename := fmt.Sprintf("%s-%s%s", shipment.TrackingNumber, c.Param("image"), proofImageTypes[contentType])
+	c.DataFromReader(http.StatusOK, -1, contentType, r, map[string]string{
+		"Content-Disposition": `attachment; filename="` + filename + `"`,
+	})
+}
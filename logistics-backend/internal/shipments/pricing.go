@@ -0,0 +1,228 @@
+package shipments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"logistics-backend/internal/geo"
+	"logistics-backend/internal/pricing"
+	"logistics-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrPricingDisabled = errors.New("no rate card configured")
+	// ErrUnlocated means a destination could not be placed, so its distance
+	// from the origin is unknown.
+	ErrUnlocated = errors.New("destination could not be located")
+)
+
+// parcels converts pieces for pricing.
+func parcels(pieces []store.Piece) []pricing.Parcel {
+	value := func(v *float64) float64 {
+		if v == nil {
+			return 0
+		}
+		return *v
+	}
+	out := make([]pricing.Parcel, len(pieces))
+	for i, p := range pieces {
+		out[i] = pricing.Parcel{
+			WeightKg: value(p.WeightKg),
+			LengthCm: value(p.LengthCm),
+			WidthCm:  value(p.WidthCm),
+			HeightCm: value(p.HeightCm),
+		}
+	}
+	return out
+}
+
+// checkServiceLevel fails with pricing.ErrUnknownServiceLevel for a level
+// the rate card does not offer. Without a rate card any level goes.
+func (s *Service) checkServiceLevel(level string) error {
+	if rates := s.opts.Rates; rates != nil && level != "" && !slices.Contains(rates.ServiceLevels, level) {
+		return pricing.ErrUnknownServiceLevel
+	}
+	return nil
+}
+
+// price charges a shipment for its pieces sent from warehouse w, or from
+// its pickup point if it is collected from an address, giving it the
+// default service level if it has none. Any earlier price is dropped: a
+// shipment whose destination or pickup point is not placed is unpriced. It
+// fails like pricing.RateCard.Quote.
+func (s *Service) price(sh *store.Shipment, w *store.Warehouse, pieces []store.Piece) error {
+	rates := s.opts.Rates
+	if rates == nil {
+		return nil
+	}
+	if sh.ServiceLevel == "" {
+		sh.ServiceLevel = rates.DefaultServiceLevel
+	}
+	sh.ChargeableWeightKg, sh.Price, sh.Currency = nil, nil, ""
+	if sh.DestinationLatitude == nil || sh.DestinationLongitude == nil {
+		return nil
+	}
+	from := geo.Point{Lat: w.Latitude, Lng: w.Longitude}
+	if sh.PickupAddress != "" {
+		if sh.PickupLatitude == nil || sh.PickupLongitude == nil {
+			return nil
+		}
+		from = geo.Point{Lat: *sh.PickupLatitude, Lng: *sh.PickupLongitude}
+	}
+	to := geo.Point{Lat: *sh.DestinationLatitude, Lng: *sh.DestinationLongitude}
+	q, err := rates.Quote(geo.Distance(from, to), sh.ServiceLevel, parcels(pieces))
+	if err != nil {
+		return err
+	}
+	sh.ChargeableWeightKg, sh.Price, sh.Currency = &q.ChargeableWeightKg, &q.Total, q.Currency
+	return nil
+}
+
+// priceStored prices a stored shipment from its warehouse and pieces.
+func (s *Service) priceStored(ctx context.Context, sh *store.Shipment) error {
+	w, err := s.stores.Warehouses.GetByID(ctx, sh.OriginWarehouseID)
+	if err != nil {
+		return err
+	}
+	pieces, err := s.stores.Pieces.ListByShipment(ctx, sh.ID)
+	if err != nil {
+		return err
+	}
+	return s.price(sh, w, pieces)
+}
+
+// QuoteRequest describes a shipment to be priced before it is booked. The
+// destination is given by coordinates or, failing those, by an address to
+// geocode.
+type QuoteRequest struct {
+	OriginWarehouseID    int64
+	DestinationAddress   string
+	DestinationLatitude  *float64
+	DestinationLongitude *float64
+	ServiceLevel         string
+	Pieces               []store.Piece
+}
+
+// Quote prices a shipment the way Create would. It fails with
+// ErrPricingDisabled, ErrUnknownWarehouse, ErrUnlocated, a
+// *ValidationError for invalid pieces, or like pricing.RateCard.Quote.
+func (s *Service) Quote(ctx context.Context, q QuoteRequest) (*pricing.Quote, error) {
+	rates := s.opts.Rates
+	if rates == nil {
+		return nil, ErrPricingDisabled
+	}
+	if err := validatePieces(q.Pieces); err != nil {
+		return nil, err
+	}
+	if err := s.checkServiceLevel(q.ServiceLevel); err != nil {
+		return nil, err
+	}
+	w, err := s.stores.Warehouses.GetByID(ctx, q.OriginWarehouseID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUnknownWarehouse
+	} else if err != nil {
+		return nil, err
+	}
+
+	dest := &store.Shipment{
+		DestinationAddress:   q.DestinationAddress,
+		DestinationLatitude:  q.DestinationLatitude,
+		DestinationLongitude: q.DestinationLongitude,
+	}
+	s.locate(ctx, dest)
+	if dest.DestinationLatitude == nil || dest.DestinationLongitude == nil {
+		return nil, ErrUnlocated
+	}
+	from := geo.Point{Lat: w.Latitude, Lng: w.Longitude}
+	to := geo.Point{Lat: *dest.DestinationLatitude, Lng: *dest.DestinationLongitude}
+	return rates.Quote(geo.Distance(from, to), q.ServiceLevel, parcels(q.Pieces))
+}
+
+// destinationErrors checks a destination given as coordinates or, failing
+// those, as an address to geocode, the way quotes and bookings take it.
+func destinationErrors(address string, lat, lng *float64) map[string]string {
+	invalid := make(map[string]string)
+	switch {
+	case (lat == nil) != (lng == nil):
+		invalid["destination_latitude"] = "must be given together with destination_longitude"
+	case lat != nil && (*lat < -90 || *lat > 90):
+		invalid["destination_latitude"] = "must be between -90 and 90"
+	case lng != nil && (*lng < -180 || *lng > 180):
+		invalid["destination_longitude"] = "must be between -180 and 180"
+	case lat == nil && address == "":
+		invalid["destination_address"] = "is required without destination coordinates"
+	}
+	return invalid
+}
+
+// CreateQuote prices a shipment without booking it. The body carries
+// origin_warehouse_id, the destination as destination_latitude and
+// destination_longitude or as a destination_address to geocode, an
+// optional service_level and the pieces as for CreateShipment.
+func (h *Handler) CreateQuote(c *gin.Context) {
+	var body struct {
+		OriginWarehouseID    int64        `json:"origin_warehouse_id"`
+		DestinationAddress   string       `json:"destination_address"`
+		DestinationLatitude  *float64     `json:"destination_latitude"`
+		DestinationLongitude *float64     `json:"destination_longitude"`
+		ServiceLevel         string       `json:"service_level"`
+		Pieces               []pieceInput `json:"pieces"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lat, lng := body.DestinationLatitude, body.DestinationLongitude
+	address := strings.TrimSpace(body.DestinationAddress)
+	if invalid := destinationErrors(address, lat, lng); len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quote request", "fields": invalid})
+		return
+	}
+
+	pieces := make([]store.Piece, len(body.Pieces))
+	for i, in := range body.Pieces {
+		pieces[i] = in.piece()
+	}
+	quote, err := h.Service.Quote(c.Request.Context(), QuoteRequest{
+		OriginWarehouseID:    body.OriginWarehouseID,
+		DestinationAddress:   address,
+		DestinationLatitude:  lat,
+		DestinationLongitude: lng,
+		ServiceLevel:         body.ServiceLevel,
+		Pieces:               pieces,
+	})
+	var bad *ValidationError
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrPricingDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Pricing is not configured"})
+		return
+	case errors.Is(err, ErrUnknownWarehouse):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid origin warehouse"})
+		return
+	case errors.As(err, &bad):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pieces", "fields": bad.Fields})
+		return
+	case errors.Is(err, pricing.ErrUnknownServiceLevel):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quote request", "fields": map[string]string{
+			"service_level": "must be one of " + strings.Join(h.Service.opts.Rates.ServiceLevels, ", "),
+		}})
+		return
+	case errors.Is(err, ErrUnlocated):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Destination address could not be located, give its coordinates instead"})
+		return
+	case errors.Is(err, pricing.ErrOutOfArea):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Destination is outside the service area"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute quote", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
@@ -0,0 +1,363 @@
+package shipments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"logistics-backend/internal/pricing"
+	"logistics-backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Reasons a shipment is sent back for.
+const (
+	ReturnDamaged        = "damaged"
+	ReturnWrongItem      = "wrong_item"
+	ReturnNotAsDescribed = "not_as_described"
+	ReturnUnwanted       = "no_longer_needed"
+	ReturnDeliveryFailed = "delivery_failed"
+	ReturnOther          = "other"
+)
+
+var returnReasons = []string{
+	ReturnDamaged, ReturnWrongItem, ReturnNotAsDescribed, ReturnUnwanted, ReturnDeliveryFailed, ReturnOther,
+}
+
+var (
+	ErrUnknownReturnReason = errors.New("unknown return reason")
+	// ErrNotReturnable rejects returns of shipments that were neither
+	// delivered nor failed, and of return shipments themselves.
+	ErrNotReturnable = errors.New("shipment cannot be returned")
+	// ErrReattemptPending keeps customers from calling back a failed
+	// shipment, which is reattempted until the attempt limit sends it back.
+	ErrReattemptPending = errors.New("failed shipment is due for another attempt")
+)
+
+// rmaSequence names the counter RMA numbers are drawn from.
+const rmaSequence = "rma"
+
+// rmaNumber formats the n-th return merchandise authorization number.
+func rmaNumber(n int64) string {
+	return fmt.Sprintf("RMA%08d", n)
+}
+
+// Return is a request to send a shipment back.
+type Return struct {
+	ShipmentID int64
+	Reason     string
+	Notes      string
+	ActorID    int64
+	ActorRole  string
+}
+
+// CreateReturn opens a return for a delivered or failed shipment under a
+// fresh RMA number. A delivered shipment is sent back as a new pending
+// shipment of the same customer and pieces, picked up at the original
+// destination and bound for the original's origin warehouse. A failed
+// shipment never left the carrier, so it makes its own way back instead:
+// it is moved to return_to_origin, so that it is no longer reattempted,
+// and the RMA links it to itself. Only managers may cut a failed
+// shipment's attempts short; customers get ErrReattemptPending.
+//
+// Customers may only return their own shipments; others get
+// store.ErrNotFound. A shipment has at most one return: a second one fails
+// with store.ErrDuplicate. It also fails with ErrUnknownReturnReason,
+// ErrNotReturnable or like pricing.RateCard.Quote.
+func (s *Service) CreateReturn(ctx context.Context, r Return) (*store.ShipmentReturn, *store.Shipment, error) {
+	if !slices.Contains(returnReasons, r.Reason) {
+		return nil, nil, ErrUnknownReturnReason
+	}
+	// Geocode before the transaction, so a slow lookup holds no locks.
+	pickup, err := s.locatePickup(ctx, r.ShipmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var link *store.ShipmentReturn
+	var shipment *store.Shipment
+	err = s.stores.Tx.WithinTx(ctx, func(tx *store.Stores) error {
+		svc := s.withStores(tx)
+		original, err := tx.Shipments.GetByID(ctx, r.ShipmentID)
+		if err != nil {
+			return err
+		}
+		if r.ActorRole == "customer" && original.CustomerID != r.ActorID {
+			return store.ErrNotFound
+		}
+		if original.Status != StatusDelivered && original.Status != StatusFailed {
+			return ErrNotReturnable
+		}
+		existing, err := tx.Returns.GetByShipment(ctx, original.ID)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+		case err != nil:
+			return err
+		case existing.OriginalShipmentID == original.ID:
+			return store.ErrDuplicate
+		default:
+			return ErrNotReturnable
+		}
+
+		n, err := tx.Sequences.Next(ctx, rmaSequence)
+		if err != nil {
+			return err
+		}
+		link = &store.ShipmentReturn{
+			RMANumber:          rmaNumber(n),
+			OriginalShipmentID: original.ID,
+			Reason:             r.Reason,
+			Notes:              r.Notes,
+			RequestedBy:        &r.ActorID,
+			RequestedRole:      r.ActorRole,
+		}
+
+		if original.Status == StatusFailed && r.ActorRole == "customer" {
+			return ErrReattemptPending
+		}
+		if original.Status == StatusFailed {
+			_, err := svc.ChangeStatus(ctx, Transition{
+				ShipmentID: original.ID,
+				To:         StatusReturnToOrigin,
+				ActorID:    r.ActorID,
+				ActorRole:  r.ActorRole,
+				Reason:     "Return " + link.RMANumber + " opened",
+			})
+			if err != nil {
+				return err
+			}
+			link.ReturnShipmentID = original.ID
+		} else {
+			if shipment, err = svc.reverse(ctx, original, pickup); err != nil {
+				return err
+			}
+			link.ReturnShipmentID = shipment.ID
+			link.PickupAddress = shipment.PickupAddress
+			link.PickupLatitude = shipment.PickupLatitude
+			link.PickupLongitude = shipment.PickupLongitude
+		}
+
+		if err := tx.Returns.Create(ctx, link); err != nil {
+			return err
+		}
+		// Read both back for the values the database filled in.
+		if link, err = tx.Returns.GetByRMA(ctx, link.RMANumber); err != nil {
+			return err
+		}
+		shipment, err = tx.Shipments.GetByID(ctx, link.ReturnShipmentID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return link, shipment, nil
+}
+
+// locatePickup geocodes where a shipment would be collected from for its
+// return, if it was delivered without being placed. It returns nil when
+// there is nothing to look up.
+func (s *Service) locatePickup(ctx context.Context, id int64) (*store.Shipment, error) {
+	original, err := s.stores.Shipments.GetByID(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		// CreateReturn reports it.
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if original.Status != StatusDelivered || original.DestinationLatitude != nil || original.DestinationAddress == "" {
+		return nil, nil
+	}
+	at := &store.Shipment{DestinationAddress: original.DestinationAddress}
+	s.locate(ctx, at)
+	return at, nil
+}
+
+// reverse books the way back of a delivered shipment: a new shipment of the
+// same customer and pieces, picked up at the original destination and
+// bound for the original's origin warehouse. located holds the pickup
+// point locatePickup found, if any.
+func (s *Service) reverse(ctx context.Context, original, located *store.Shipment) (*store.Shipment, error) {
+	warehouse, err := s.stores.Warehouses.GetByID(ctx, original.OriginWarehouseID)
+	if err != nil {
+		return nil, err
+	}
+	originals, err := s.stores.Pieces.ListByShipment(ctx, original.ID)
+	if err != nil {
+		return nil, err
+	}
+	pieces := make([]store.Piece, len(originals))
+	for i, p := range originals {
+		pieces[i] = store.Piece{
+			Description:   p.Description,
+			WeightKg:      p.WeightKg,
+			LengthCm:      p.LengthCm,
+			WidthCm:       p.WidthCm,
+			HeightCm:      p.HeightCm,
+			DeclaredValue: p.DeclaredValue,
+		}
+	}
+	// Warehouses have no postal address; the name and hub code are what
+	// the label and the driver need.
+	address := warehouse.Name
+	if warehouse.Code != "" {
+		address += " (" + warehouse.Code + ")"
+	}
+	lat, lng := warehouse.Latitude, warehouse.Longitude
+	// A shipment booked by coordinates alone is collected from them.
+	pickup := original.DestinationAddress
+	if pickup == "" && original.DestinationLatitude != nil && original.DestinationLongitude != nil {
+		pickup = fmt.Sprintf("%.6f, %.6f", *original.DestinationLatitude, *original.DestinationLongitude)
+	}
+	shipment := &store.Shipment{
+		OriginWarehouseID:    original.OriginWarehouseID,
+		PickupAddress:        pickup,
+		PickupLatitude:       original.DestinationLatitude,
+		PickupLongitude:      original.DestinationLongitude,
+		DestinationAddress:   address,
+		DestinationLatitude:  &lat,
+		DestinationLongitude: &lng,
+		CustomerID:           original.CustomerID,
+		ServiceLevel:         original.ServiceLevel,
+	}
+	// The return is priced from its pickup point, which the original may
+	// have been delivered to without ever being placed.
+	if shipment.PickupLatitude == nil && located != nil {
+		shipment.PickupLatitude, shipment.PickupLongitude = located.DestinationLatitude, located.DestinationLongitude
+	}
+	if _, err := s.Create(ctx, shipment, pieces); err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// CreateShipmentReturn opens a return for a shipment. The body carries the
+// reason code and optional notes.
+func (h *Handler) CreateShipmentReturn(c *gin.Context) {
+	id, ok := shipmentID(c)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+		Notes  string `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invalid := make(map[string]string)
+	if !slices.Contains(returnReasons, body.Reason) {
+		invalid["reason"] = "must be one of " + strings.Join(returnReasons, ", ")
+	}
+	notes := strings.TrimSpace(body.Notes)
+	if len(notes) > 512 {
+		invalid["notes"] = "must be at most 512 characters"
+	}
+	if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return", "fields": invalid})
+		return
+	}
+
+	link, shipment, err := h.Service.CreateReturn(c.Request.Context(), Return{
+		ShipmentID: id,
+		Reason:     body.Reason,
+		Notes:      notes,
+		ActorID:    int64(c.GetInt("user_id")),
+		ActorRole:  c.GetString("role"),
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return
+	case errors.Is(err, ErrNotReturnable):
+		c.JSON(http.StatusConflict, gin.H{"error": "Only delivered or failed shipments can be returned"})
+		return
+	case errors.Is(err, ErrReattemptPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "A failed delivery is reattempted before it is returned"})
+		return
+	case errors.Is(err, pricing.ErrOutOfArea):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pickup address is outside the service area"})
+		return
+	case errors.Is(err, pricing.ErrUnknownServiceLevel):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The shipment's service level is no longer offered"})
+		return
+	case errors.Is(err, store.ErrDuplicate):
+		c.JSON(http.StatusConflict, gin.H{"error": "A return is already open for this shipment"})
+		return
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Shipment status changed concurrently, please retry"})
+		return
+	default:
+		log.Printf("Creating a return for shipment %d failed: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create return"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Return created",
+		"return":   link,
+		"shipment": shipment,
+	})
+}
+
+// GetShipmentReturn shows the return a shipment belongs to, whether as the
+// original or as the return shipment.
+func (h *Handler) GetShipmentReturn(c *gin.Context) {
+	id, ok := shipmentID(c)
+	if !ok {
+		return
+	}
+
+	// Customers should only see their own shipment
+	shipment, err := h.visibleShipment(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch return"})
+		return
+	}
+	if shipment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return
+	}
+
+	link, err := h.Returns.GetByShipment(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment has no return"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch return", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, link)
+}
+
+// GetReturnByRMA looks a return up by its RMA number.
+func (h *Handler) GetReturnByRMA(c *gin.Context) {
+	link, err := h.Returns.GetByRMA(c.Request.Context(), strings.ToUpper(c.Param("rma")))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch return", "details": err.Error()})
+		return
+	}
+
+	// Customers should only see their own returns
+	shipment, err := h.visibleShipment(c, link.ReturnShipmentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch return"})
+		return
+	}
+	if shipment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+		return
+	}
+	c.JSON(http.StatusOK, link)
+}
@@ -0,0 +1,277 @@
+package shipments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"logistics-backend/internal/store"
+	"logistics-backend/internal/tracking"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kinds of barcode scan a driver makes along the way.
+const (
+	ScanPickup         = "pickup"
+	ScanHubArrival     = "hub_arrival"
+	ScanOutForDelivery = "out_for_delivery"
+	ScanDelivered      = "delivered"
+)
+
+var scanTypes = []string{ScanPickup, ScanHubArrival, ScanOutForDelivery, ScanDelivered}
+
+// scanTransitions maps every scan type to the statuses it may be made in
+// and the status it moves a shipment or piece on to. A scan that keeps the
+// status, such as arriving at a hub while in transit, is only recorded.
+var scanTransitions = map[string]map[string]string{
+	ScanPickup: {
+		StatusPending: StatusInTransit,
+	},
+	ScanHubArrival: {
+		StatusInTransit:      StatusInTransit,
+		StatusReturnToOrigin: StatusReturnToOrigin,
+	},
+	ScanOutForDelivery: {
+		StatusInTransit: StatusInTransit,
+		// Going out again after a failed attempt is the reattempt.
+		StatusFailed: StatusInTransit,
+	},
+	ScanDelivered: {
+		StatusInTransit: StatusDelivered,
+	},
+}
+
+var ErrUnknownScanType = errors.New("unknown scan type")
+
+// ScanError reports a scan that does not fit the status of what was
+// scanned, such as delivering a shipment that was never picked up.
+type ScanError struct {
+	Type, Status string
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("cannot scan %s while %s", e.Type, e.Status)
+}
+
+// Scan is a barcode read by a driver's device.
+type Scan struct {
+	// Barcode is either a tracking number or a piece barcode.
+	Barcode   string
+	Type      string
+	ActorID   int64
+	ActorRole string
+	Latitude  *float64
+	Longitude *float64
+	// ScannedAt is the device's clock at the time of the scan, which may
+	// be well before the scan reaches the server.
+	ScannedAt time.Time
+}
+
+// ScanResult is what a scan resolved to and the event it was recorded as.
+type ScanResult struct {
+	Shipment *store.Shipment
+	// Piece is set when a piece barcode was scanned.
+	Piece *store.Piece
+	Event *store.ShipmentEvent
+}
+
+// Scan resolves a scanned barcode to its shipment, or to a single piece,
+// and applies the status change the scan type calls for, recording the scan
+// on the event. A piece barcode moves just that piece, unless the shipment
+// is in a status that applies to whole shipments only. Drivers may only
+// scan shipments assigned to them.
+//
+// It returns store.ErrNotFound for unknown barcodes, ErrUnknownScanType,
+// ErrNotAssigned, a *ScanError when the scan does not fit the current
+// status, and otherwise fails like ChangeStatus.
+func (s *Service) Scan(ctx context.Context, sc Scan) (*ScanResult, error) {
+	allowed, ok := scanTransitions[sc.Type]
+	if !ok {
+		return nil, ErrUnknownScanType
+	}
+
+	var result ScanResult
+	err := s.stores.Tx.WithinTx(ctx, func(tx *store.Stores) error {
+		barcode := tracking.Normalize(sc.Barcode)
+		shipment, err := tx.Shipments.GetByTrackingNumber(ctx, barcode)
+		var piece *store.Piece
+		if errors.Is(err, store.ErrNotFound) {
+			if piece, err = tx.Pieces.GetByBarcode(ctx, barcode); err != nil {
+				return err
+			}
+			shipment, err = tx.Shipments.GetByID(ctx, piece.ShipmentID)
+		}
+		if err != nil {
+			return err
+		}
+		if sc.ActorRole == "driver" && (shipment.DriverID == nil || *shipment.DriverID != sc.ActorID) {
+			return ErrNotAssigned
+		}
+
+		from := shipment.Status
+		if piece != nil && shipmentOnly(shipment.Status) {
+			piece = nil
+		} else if piece != nil {
+			from = piece.Status
+		}
+		to, ok := allowed[from]
+		if !ok {
+			return &ScanError{Type: sc.Type, Status: from}
+		}
+
+		scannedAt := sc.ScannedAt
+		t := Transition{
+			ShipmentID: shipment.ID,
+			To:         to,
+			ActorID:    sc.ActorID,
+			ActorRole:  sc.ActorRole,
+			Latitude:   sc.Latitude,
+			Longitude:  sc.Longitude,
+			ScanType:   sc.Type,
+			ScannedAt:  &scannedAt,
+		}
+		svc := s.withStores(tx)
+		switch {
+		case to == from:
+			actorID := sc.ActorID
+			result.Event = &store.ShipmentEvent{
+				ShipmentID: shipment.ID,
+				Status:     to,
+				Latitude:   sc.Latitude,
+				Longitude:  sc.Longitude,
+				ActorID:    &actorID,
+				ActorRole:  sc.ActorRole,
+				ScanType:   sc.Type,
+				ScannedAt:  &scannedAt,
+			}
+			if piece != nil {
+				result.Event.PieceID = &piece.ID
+			}
+			err = tx.Events.Create(ctx, result.Event)
+		case piece != nil:
+			result.Event, err = svc.ChangePieceStatus(ctx, piece.Barcode, t)
+		default:
+			result.Event, err = svc.ChangeStatus(ctx, t)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Read back what the scan changed, and the event for the timestamp
+		// the database filled in.
+		events, err := tx.Events.List(ctx, store.EventFilter{ShipmentID: shipment.ID, Statuses: []string{to}})
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if e.ID == result.Event.ID {
+				result.Event = &e
+			}
+		}
+		if piece != nil {
+			if result.Piece, err = tx.Pieces.GetByBarcode(ctx, piece.Barcode); err != nil {
+				return err
+			}
+		}
+		result.Shipment, err = tx.Shipments.GetByID(ctx, shipment.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// maxScanClockSkew is how far ahead of the server a device's clock may be.
+const maxScanClockSkew = 5 * time.Minute
+
+// RecordScan applies a barcode scan. The body carries the scanned barcode,
+// the scan type (pickup, hub_arrival, out_for_delivery or delivered;
+// hyphens are accepted in place of underscores), the latitude and
+// longitude of the device and scanned_at, its RFC 3339 timestamp.
+func (h *Handler) RecordScan(c *gin.Context) {
+	var body struct {
+		Barcode   string   `json:"barcode"`
+		Type      string   `json:"type"`
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+		ScannedAt string   `json:"scanned_at"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invalid := make(map[string]string)
+	if strings.TrimSpace(body.Barcode) == "" {
+		invalid["barcode"] = "is required"
+	}
+	scanType := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(body.Type)), "-", "_")
+	if _, ok := scanTransitions[scanType]; !ok {
+		invalid["type"] = "must be one of " + strings.Join(scanTypes, ", ")
+	}
+	if body.Latitude == nil || body.Longitude == nil {
+		invalid["latitude"] = "latitude and longitude are required"
+	} else if *body.Latitude < -90 || *body.Latitude > 90 || *body.Longitude < -180 || *body.Longitude > 180 {
+		invalid["latitude"] = "latitude and longitude are out of range"
+	}
+	scannedAt, err := time.Parse(time.RFC3339, body.ScannedAt)
+	if err != nil {
+		invalid["scanned_at"] = "must be a timestamp like 2006-01-02T15:04:05Z"
+	} else if scannedAt.After(time.Now().Add(maxScanClockSkew)) {
+		invalid["scanned_at"] = "must not be in the future"
+	}
+	if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scan", "fields": invalid})
+		return
+	}
+
+	result, err := h.Service.Scan(c.Request.Context(), Scan{
+		Barcode:   body.Barcode,
+		Type:      scanType,
+		ActorID:   int64(c.GetInt("user_id")),
+		ActorRole: c.GetString("role"),
+		Latitude:  body.Latitude,
+		Longitude: body.Longitude,
+		ScannedAt: scannedAt.UTC(),
+	})
+	var mismatch *ScanError
+	var illegal *TransitionError
+	switch {
+	case err == nil:
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "No shipment or piece has this barcode"})
+		return
+	case errors.Is(err, ErrNotAssigned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own shipments"})
+		return
+	case errors.As(err, &mismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": mismatch.Error(), "type": mismatch.Type, "status": mismatch.Status})
+		return
+	case errors.As(err, &illegal):
+		c.JSON(http.StatusConflict, gin.H{"error": illegal.Error(), "from": illegal.From, "to": illegal.To})
+		return
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Shipment status changed concurrently, please retry"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record scan", "details": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"message":         "Scan recorded",
+		"shipment_id":     result.Shipment.ID,
+		"tracking_number": result.Shipment.TrackingNumber,
+		"shipment_status": result.Shipment.Status,
+		"event":           result.Event,
+	}
+	if result.Piece != nil {
+		response["piece"] = result.Piece
+	}
+	c.JSON(http.StatusCreated, response)
+}
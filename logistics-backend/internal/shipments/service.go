@@ -0,0 +1,286 @@
+package shipments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"logistics-backend/internal/blob"
+	"logistics-backend/internal/geocode"
+	"logistics-backend/internal/pricing"
+	"logistics-backend/internal/store"
+	"logistics-backend/internal/tracking"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusInTransit = "in_transit"
+	StatusDelivered = "delivered"
+	StatusCancelled = "cancelled"
+	// StatusFailed follows a failed delivery attempt; the shipment waits
+	// for its reattempt, which puts it back in transit.
+	StatusFailed = "failed"
+	// StatusReturnToOrigin is taken after the last allowed attempt failed,
+	// and StatusReturned once the shipment is back at its origin.
+	StatusReturnToOrigin = "return_to_origin"
+	StatusReturned       = "returned"
+)
+
+// transitions lists, for every status, the statuses a shipment may move to
+// next. Statuses without an entry are terminal.
+var transitions = map[string][]string{
+	StatusPending:        {StatusInTransit, StatusCancelled},
+	StatusInTransit:      {StatusDelivered, StatusFailed, StatusCancelled},
+	StatusFailed:         {StatusInTransit, StatusReturnToOrigin, StatusCancelled},
+	StatusReturnToOrigin: {StatusReturned},
+	StatusDelivered:      nil,
+	StatusCancelled:      nil,
+	StatusReturned:       nil,
+}
+
+var (
+	ErrUnknownStatus = errors.New("unknown shipment status")
+	ErrNotAssigned   = errors.New("shipment is not assigned to this driver")
+)
+
+// TransitionError reports a status change the state machine does not allow.
+type TransitionError struct {
+	From, To string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot change status from %s to %s", e.From, e.To)
+}
+
+// ValidStatus reports whether status is part of the state machine.
+func ValidStatus(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// shipmentOnly reports whether status applies to whole shipments only, so
+// that single pieces cannot be moved to it.
+func shipmentOnly(status string) bool {
+	return status == StatusFailed || status == StatusReturnToOrigin || status == StatusReturned
+}
+
+// CanTransition reports whether a shipment in status from may move to to.
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// predecessors lists the statuses that may move to status.
+func predecessors(status string) []string {
+	var from []string
+	for st := range transitions {
+		if CanTransition(st, status) {
+			from = append(from, st)
+		}
+	}
+	return from
+}
+
+// Transition is a requested status change and who asked for it.
+type Transition struct {
+	ShipmentID int64
+	To         string
+	ActorID    int64
+	ActorRole  string
+	Reason     string
+	Latitude   *float64
+	Longitude  *float64
+	// ScanType and ScannedAt are set when the change comes from a barcode
+	// scan; see Scan.
+	ScanType  string
+	ScannedAt *time.Time
+}
+
+// Service creates shipments and applies their status changes. Every
+// accepted change is written together with its shipment_events row, so the
+// timeline never disagrees with the shipment's current status.
+type Service struct {
+	stores  *store.Stores
+	numbers *tracking.Generator
+	opts    Options
+}
+
+// Options configures what a Service needs besides its stores.
+type Options struct {
+	// Blobs keeps proof-of-delivery images.
+	Blobs blob.Store
+	// Geocoder places destination addresses; nil leaves them unplaced.
+	Geocoder geocode.Geocoder
+	// MaxAttempts is the number of failed delivery attempts after which a
+	// shipment returns to origin.
+	MaxAttempts int
+	// Rates prices shipments; nil leaves them unpriced.
+	Rates *pricing.RateCard
+}
+
+func NewService(stores *store.Stores, numbers *tracking.Generator, opts Options) *Service {
+	return &Service{stores: stores, numbers: numbers, opts: opts}
+}
+
+// withStores returns a Service working on tx, typically the stores of a
+// running transaction.
+func (s *Service) withStores(tx *store.Stores) *Service {
+	return NewService(tx, tracking.NewGenerator(s.numbers.Format(), tx.Sequences), s.opts)
+}
+
+// locate geocodes the destination address of a shipment that has no
+// destination coordinates yet. A shipment the geocoder cannot place is
+// flagged for a manual pin instead. So is one whose lookup failed outright:
+// the failure is logged, but it must not stop the shipment from being
+// booked.
+func (s *Service) locate(ctx context.Context, sh *store.Shipment) {
+	if s.opts.Geocoder == nil || sh.DestinationLatitude != nil {
+		return
+	}
+	p, err := s.opts.Geocoder.Geocode(ctx, sh.DestinationAddress)
+	if err != nil {
+		if !errors.Is(err, geocode.ErrNoMatch) {
+			log.Printf("Geocoding %q failed: %v", sh.DestinationAddress, err)
+		}
+		sh.NeedsManualPin = true
+		return
+	}
+	sh.DestinationLatitude, sh.DestinationLongitude = &p.Lat, &p.Lng
+	sh.NeedsManualPin = false
+}
+
+// maxNumberAttempts bounds how often Create draws a new tracking number
+// after hitting one that is already taken.
+const maxNumberAttempts = 5
+
+var ErrUnknownWarehouse = errors.New("unknown origin warehouse")
+
+// Create assigns the shipment a generated tracking number and stores it as
+// pending together with its pieces, numbered in order. A shipment given no
+// pieces gets a single one without dimensions. A number can collide with
+// one entered before generation was introduced; Create then moves on to the
+// next sequence value.
+//
+// A shipment given no destination coordinates is geocoded from its
+// address, see locate, and priced at its service level, see price. Create
+// fails like pricing.RateCard.Quote when it cannot be priced.
+func (s *Service) Create(ctx context.Context, sh *store.Shipment, pieces []store.Piece) ([]store.Piece, error) {
+	if err := validatePieces(pieces); err != nil {
+		return nil, err
+	}
+	if err := s.checkServiceLevel(sh.ServiceLevel); err != nil {
+		return nil, err
+	}
+	if len(pieces) == 0 {
+		pieces = []store.Piece{{}}
+	}
+	s.locate(ctx, sh)
+
+	err := s.stores.Tx.WithinTx(ctx, func(tx *store.Stores) error {
+		warehouse, err := tx.Warehouses.GetByID(ctx, sh.OriginWarehouseID)
+		if errors.Is(err, store.ErrNotFound) {
+			return ErrUnknownWarehouse
+		} else if err != nil {
+			return err
+		}
+		if err := s.price(sh, warehouse, pieces); err != nil {
+			return err
+		}
+
+		numbers := s.withStores(tx).numbers
+		sh.Status = StatusPending
+		for attempt := 1; ; attempt++ {
+			if sh.TrackingNumber, err = numbers.Next(ctx, warehouse); err != nil {
+				return err
+			}
+			// Look before inserting: a failed insert aborts the whole
+			// transaction on PostgreSQL.
+			_, err = tx.Shipments.GetByTrackingNumber(ctx, sh.TrackingNumber)
+			if err == nil {
+				err = store.ErrDuplicate
+			} else if errors.Is(err, store.ErrNotFound) {
+				err = tx.Shipments.Create(ctx, sh)
+			}
+			if !errors.Is(err, store.ErrDuplicate) || attempt == maxNumberAttempts {
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		for i := range pieces {
+			p := &pieces[i]
+			p.ShipmentID = sh.ID
+			p.Number = i + 1
+			p.Barcode = PieceBarcode(sh.TrackingNumber, p.Number)
+			p.Status = StatusPending
+			if err := tx.Pieces.Create(ctx, p); err != nil {
+				return err
+			}
+		}
+		// Read the pieces back for the defaults the database filled in.
+		pieces, err = tx.Pieces.ListByShipment(ctx, sh.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pieces, nil
+}
+
+// ChangeStatus moves a shipment to t.To and records the event. Drivers may
+// only move shipments assigned to them. It returns store.ErrNotFound,
+// ErrUnknownStatus, ErrNotAssigned or a *TransitionError when the change is
+// rejected, and store.ErrConflict if the shipment changed concurrently.
+func (s *Service) ChangeStatus(ctx context.Context, t Transition) (*store.ShipmentEvent, error) {
+	if !ValidStatus(t.To) {
+		return nil, ErrUnknownStatus
+	}
+
+	var event *store.ShipmentEvent
+	err := s.stores.Tx.WithinTx(ctx, func(tx *store.Stores) error {
+		shipment, err := tx.Shipments.GetByID(ctx, t.ShipmentID)
+		if err != nil {
+			return err
+		}
+		if t.ActorRole == "driver" && (shipment.DriverID == nil || *shipment.DriverID != t.ActorID) {
+			return ErrNotAssigned
+		}
+		if !CanTransition(shipment.Status, t.To) {
+			return &TransitionError{From: shipment.Status, To: t.To}
+		}
+
+		if err := tx.Shipments.UpdateStatus(ctx, shipment.ID, shipment.Status, t.To); err != nil {
+			return err
+		}
+		// Pieces follow the shipment as a whole.
+		if err := tx.Pieces.UpdateStatuses(ctx, shipment.ID, predecessors(t.To), t.To); err != nil {
+			return err
+		}
+
+		actorID := t.ActorID
+		event = &store.ShipmentEvent{
+			ShipmentID: shipment.ID,
+			Status:     t.To,
+			Latitude:   t.Latitude,
+			Longitude:  t.Longitude,
+			ActorID:    &actorID,
+			ActorRole:  t.ActorRole,
+			Reason:     t.Reason,
+			ScanType:   t.ScanType,
+			ScannedAt:  t.ScannedAt,
+		}
+		return tx.Events.Create(ctx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
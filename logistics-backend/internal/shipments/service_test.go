@@ -0,0 +1,58 @@
+package shipments
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	allowed := map[string][]string{
+		StatusPending:        {StatusInTransit, StatusCancelled},
+		StatusInTransit:      {StatusDelivered, StatusFailed, StatusCancelled},
+		StatusFailed:         {StatusInTransit, StatusReturnToOrigin, StatusCancelled},
+		StatusReturnToOrigin: {StatusReturned},
+		// Terminal.
+		StatusDelivered: nil,
+		StatusCancelled: nil,
+		StatusReturned:  nil,
+	}
+	statuses := []string{
+		StatusPending, StatusInTransit, StatusDelivered, StatusCancelled,
+		StatusFailed, StatusReturnToOrigin, StatusReturned,
+	}
+	if len(transitions) != len(statuses) {
+		t.Fatalf("%d statuses in the state machine, want %d", len(transitions), len(statuses))
+	}
+	for _, from := range statuses {
+		if !ValidStatus(from) {
+			t.Errorf("%s is not a valid status", from)
+		}
+		// Every pair, including staying put and the unknown status.
+		for _, to := range append(statuses, "lost") {
+			want := slices.Contains(allowed[from], to)
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+	if ValidStatus("lost") || CanTransition("lost", StatusPending) {
+		t.Error("an unknown status is part of the state machine")
+	}
+}
+
+func TestPredecessors(t *testing.T) {
+	tests := map[string][]string{
+		StatusPending:        nil,
+		StatusInTransit:      {StatusFailed, StatusPending},
+		StatusCancelled:      {StatusFailed, StatusInTransit, StatusPending},
+		StatusReturned:       {StatusReturnToOrigin},
+		StatusReturnToOrigin: {StatusFailed},
+	}
+	for status, want := range tests {
+		got := predecessors(status)
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("predecessors(%s) = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -1,45 +1,109 @@
 package shipments
 
 import (
-	"database/sql"
-	"log"
+	"errors"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 
-	"logistics-backend/internal/database"
+	"logistics-backend/internal/eta"
+	"logistics-backend/internal/pricing"
+	"logistics-backend/internal/store"
+	"logistics-backend/internal/tracking"
 
 	"github.com/gin-gonic/gin"
 )
 
-type Shipment struct {
-	ID              int64     `json:"id"`
-	TrackingNumber  string    `json:"tracking_number"`
-	OriginWarehouse int64     `json:"origin_warehouse_id"`
-	DestinationAddr string    `json:"destination_address"`
-	CustomerID      int64     `json:"customer_id"`
-	Status          string    `json:"status"`
-	CreatedAt       time.Time `json:"created_at"`
+type Handler struct {
+	Shipments  store.ShipmentStore
+	Pieces     store.PieceStore
+	Users      store.UserStore
+	Warehouses store.WarehouseStore
+	Events     store.EventStore
+	Changes    store.ChangeStore
+	Proofs     store.ProofStore
+	Attempts   store.AttemptStore
+	Returns    store.ReturnStore
+	Service    *Service
 }
 
-func CreateShipment(c *gin.Context) {
+func NewHandler(stores *store.Stores, numbers *tracking.Generator, opts Options) *Handler {
+	return &Handler{
+		Shipments:  stores.Shipments,
+		Pieces:     stores.Pieces,
+		Users:      stores.Users,
+		Warehouses: stores.Warehouses,
+		Events:     stores.Events,
+		Changes:    stores.Changes,
+		Proofs:     stores.Proofs,
+		Attempts:   stores.Attempts,
+		Returns:    stores.Returns,
+		Service:    NewService(stores, numbers, opts),
+	}
+}
+
+// shipmentID parses the :id path parameter, answering 400 when it is not a
+// number.
+func shipmentID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shipment ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// CreateShipment books a shipment. Its destination is taken as for
+// CreateQuote, so a booking is priced like the quote that preceded it.
+func (h *Handler) CreateShipment(c *gin.Context) {
+	// Tracking numbers are generated, so any tracking_number sent is ignored.
 	var input struct {
-		TrackingNumber  string `json:"tracking_number"`
-		OriginWarehouse int64  `json:"origin_warehouse_id"`
-		DestinationAddr string `json:"destination_address"`
-		CustomerID      int64  `json:"customer_id"`
+		OriginWarehouse int64        `json:"origin_warehouse_id"`
+		DestinationAddr string       `json:"destination_address"`
+		DestinationLat  *float64     `json:"destination_latitude"`
+		DestinationLng  *float64     `json:"destination_longitude"`
+		CustomerID      int64        `json:"customer_id"`
+		ServiceLevel    string       `json:"service_level"`
+		Pieces          []pieceInput `json:"pieces"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	address := strings.TrimSpace(input.DestinationAddr)
+	if invalid := destinationErrors(address, input.DestinationLat, input.DestinationLng); len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shipment", "fields": invalid})
+		return
+	}
 
-	_, err := database.DB.Exec(`
-		INSERT INTO shipments (tracking_number, origin_warehouse_id, destination_address, customer_id, status)
-		VALUES (?, ?, ?, ?, 'pending')
-	`, input.TrackingNumber, input.OriginWarehouse, input.DestinationAddr, input.CustomerID)
-
-	if err != nil {
+	shipment := &store.Shipment{
+		OriginWarehouseID:    input.OriginWarehouse,
+		DestinationAddress:   address,
+		DestinationLatitude:  input.DestinationLat,
+		DestinationLongitude: input.DestinationLng,
+		CustomerID:           input.CustomerID,
+		ServiceLevel:         input.ServiceLevel,
+	}
+	pieces := make([]store.Piece, len(input.Pieces))
+	for i, in := range input.Pieces {
+		pieces[i] = in.piece()
+	}
+	pieces, err := h.Service.Create(c.Request.Context(), shipment, pieces)
+	var invalid *ValidationError
+	if errors.Is(err, ErrUnknownWarehouse) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid origin warehouse"})
+		return
+	} else if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pieces", "fields": invalid.Fields})
+		return
+	} else if errors.Is(err, pricing.ErrUnknownServiceLevel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service level"})
+		return
+	} else if errors.Is(err, pricing.ErrOutOfArea) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Destination is outside the service area"})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create shipment",
 			"details": err.Error(),
@@ -47,36 +111,56 @@ func CreateShipment(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Shipment created"})
+	c.JSON(http.StatusCreated, gin.H{
+		"message":         "Shipment created",
+		"id":              shipment.ID,
+		"tracking_number": shipment.TrackingNumber,
+		"service_level":   shipment.ServiceLevel,
+		"price":           shipment.Price,
+		"currency":        shipment.Currency,
+		"pieces":          pieces,
+	})
 }
 
-func UpdateShipmentStatus(c *gin.Context) {
+func (h *Handler) UpdateShipmentStatus(c *gin.Context) {
 	userID := c.GetInt("user_id")
 	userRole := c.GetString("role")
 
-	id := c.Param("id")
+	id, ok := shipmentID(c)
+	if !ok {
+		return
+	}
 
 	var body struct {
-		Status string `json:"status"`
+		Status    string   `json:"status"`
+		Reason    string   `json:"reason"`
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Validate status
-	allowedStatuses := []string{"pending", "in_transit", "delivered", "cancelled"}
-	isValid := false
-	for _, s := range allowedStatuses {
-		if body.Status == s {
-			isValid = true
-			break
-		}
-	}
-	if !isValid {
+	if !ValidStatus(body.Status) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
 		return
 	}
+	// A failed delivery needs a reason code and counts towards the attempt
+	// limit, and a shipment only goes back to origin once its attempts run
+	// out.
+	switch body.Status {
+	case StatusFailed:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Record failed deliveries with POST /api/shipments/:id/attempts"})
+		return
+	case StatusReturnToOrigin:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Shipments return to origin after their last failed delivery attempt"})
+		return
+	}
+	if (body.Latitude == nil) != (body.Longitude == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "latitude and longitude must be given together"})
+		return
+	}
 
 	// Role check
 	if userRole == "customer" {
@@ -84,25 +168,31 @@ func UpdateShipmentStatus(c *gin.Context) {
 		return
 	}
 
-	// For courier, check if shipment is assigned to them
-	if userRole == "driver" {
-		var assignedCourierID int
-		err := database.DB.QueryRow(`SELECT driver_id FROM shipments WHERE id = ?`, id).Scan(&assignedCourierID)
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
-			return
-		}
-		log.Println("Assigned Driver ID:", assignedCourierID)
-		log.Println("User ID:", userID)
-		if assignedCourierID != userID {
-			c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own shipments"})
-			return
-		}
-	}
-
-	// Update status
-	_, err := database.DB.Exec(`UPDATE shipments SET status = ? WHERE id = ?`, body.Status, id)
-	if err != nil {
+	_, err := h.Service.ChangeStatus(c.Request.Context(), Transition{
+		ShipmentID: id,
+		To:         body.Status,
+		ActorID:    int64(userID),
+		ActorRole:  userRole,
+		Reason:     body.Reason,
+		Latitude:   body.Latitude,
+		Longitude:  body.Longitude,
+	})
+	var illegal *TransitionError
+	switch {
+	case err == nil:
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return
+	case errors.Is(err, ErrNotAssigned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own shipments"})
+		return
+	case errors.As(err, &illegal):
+		c.JSON(http.StatusConflict, gin.H{"error": illegal.Error(), "from": illegal.From, "to": illegal.To})
+		return
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Shipment status changed concurrently, please retry"})
+		return
+	default:
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update status",
 			"details": err.Error()})
@@ -112,7 +202,7 @@ func UpdateShipmentStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Status updated successfully"})
 }
 
-func AssignShipmentToCourier(c *gin.Context) {
+func (h *Handler) AssignShipmentToCourier(c *gin.Context) {
 	userRole := c.GetString("role")
 
 	// Only managers can assign shipments
@@ -121,10 +211,13 @@ func AssignShipmentToCourier(c *gin.Context) {
 		return
 	}
 
-	id := c.Param("id")
+	id, ok := shipmentID(c)
+	if !ok {
+		return
+	}
 
 	var body struct {
-		DriverId int `json:"driver"`
+		DriverId int64 `json:"driver"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -132,11 +225,7 @@ func AssignShipmentToCourier(c *gin.Context) {
 	}
 
 	// Validate courier exists
-	var exists bool
-	err := database.DB.QueryRow(
-		`SELECT EXISTS(SELECT 1 FROM users WHERE id = ? AND role = 'driver')`,
-		body.DriverId,
-	).Scan(&exists)
+	exists, err := h.Users.ExistsWithRole(c.Request.Context(), body.DriverId, "driver")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error", "details": err.Error()})
 		return
@@ -147,195 +236,102 @@ func AssignShipmentToCourier(c *gin.Context) {
 	}
 
 	// Update the shipment's courier
-	res, err := database.DB.Exec(
-		`UPDATE shipments SET driver_id = ? WHERE id = ?`,
-		body.DriverId, id,
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign courier", "details": err.Error()})
-		return
-	}
-
-	rowsAffected, _ := res.RowsAffected()
-	if rowsAffected == 0 {
+	err = h.Shipments.AssignDriver(c.Request.Context(), id, body.DriverId)
+	if errors.Is(err, store.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
 		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign courier", "details": err.Error()})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Courier assigned successfully"})
 }
 
-func GetShipmentData(c *gin.Context) {
-	role := c.GetString("role")
-	userID := c.GetInt("user_id")
-
-	id := c.Param("id")
-
-	var rows *sql.Rows
-	var err error
+// visibleShipment loads a shipment and hides it from customers who do not
+// own it. A nil shipment with a nil error means "not visible".
+func (h *Handler) visibleShipment(c *gin.Context, id int64) (*store.Shipment, error) {
+	shipment, err := h.Shipments.GetByID(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if c.GetString("role") == "customer" && shipment.CustomerID != int64(c.GetInt("user_id")) {
+		return nil, nil
+	}
+	return shipment, nil
+}
 
-	if role == "customer" {
-		rows, err = database.DB.Query(`
-            SELECT id, tracking_number, origin_warehouse_id, destination_address, customer_id, status
-            FROM shipments
-            WHERE customer_id = ? and id = ?`, userID, id)
-	} else {
-		// manager & driver
-		rows, err = database.DB.Query(`
-            SELECT id, tracking_number, origin_warehouse_id, destination_address, customer_id, status
-            FROM shipments WHERE id = ?`, id)
+func (h *Handler) GetShipmentData(c *gin.Context) {
+	id, ok := shipmentID(c)
+	if !ok {
+		return
 	}
 
+	shipment, err := h.visibleShipment(c, id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipments"})
 		return
 	}
-	defer rows.Close()
 
-	type Shipment struct {
-		ID                 int    `json:"id"`
-		TrackingNumber     string `json:"tracking_number"`
-		OriginWarehouseID  int    `json:"origin_warehouse_id"`
-		DestinationAddress string `json:"destination_address"`
-		CustomerID         int    `json:"customer_id"`
-		Status             string `json:"status"`
+	// The estimate, when there is one, is inlined into the shipment.
+	type shipmentDetail struct {
+		store.Shipment
+		*eta.Estimate
 	}
-
-	var shipments []Shipment
-
-	for rows.Next() {
-		var s Shipment
-		if err := rows.Scan(&s.ID, &s.TrackingNumber, &s.OriginWarehouseID, &s.DestinationAddress, &s.CustomerID, &s.Status); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse shipments"})
+	shipments := []shipmentDetail{}
+	if shipment != nil {
+		estimate, err := h.estimate(c.Request.Context(), shipment)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipments"})
 			return
 		}
-		shipments = append(shipments, s)
+		shipments = append(shipments, shipmentDetail{*shipment, estimate})
+		c.Header("ETag", etag(shipment))
 	}
 
 	c.JSON(http.StatusOK, shipments)
 }
 
-func GetShipmentCoordinates(c *gin.Context) {
-	role := c.GetString("role")
-
-	var rows *sql.Rows
-	var err error
-
-	// Adjust query based on role
-	if role == "customer" {
-		rows, err = database.DB.Query(`
-			SELECT shipment_id AS id, tracking_number, lat, lng
-			FROM (
-				SELECT 
-					se.shipment_id,
-					s.tracking_number,
-					se.latitude AS lat,
-					se.longitude AS lng,
-					ROW_NUMBER() OVER (PARTITION BY se.shipment_id ORDER BY se.timestamp DESC) AS rn
-				FROM shipment_events se
-				LEFT JOIN shipments s ON s.id = se.shipment_id
-			) t
-			WHERE rn = 1
-			ORDER BY shipment_id;`)
-	} else {
-		rows, err = database.DB.Query(`
-			SELECT shipment_id AS id, tracking_number, lat, lng
-			FROM (
-				SELECT 
-					se.shipment_id,
-					s.tracking_number,
-					se.latitude AS lat,
-					se.longitude AS lng,
-					ROW_NUMBER() OVER (PARTITION BY se.shipment_id ORDER BY se.timestamp DESC) AS rn
-				FROM shipment_events se
-				LEFT JOIN shipments s ON s.id = se.shipment_id
-			) t
-			WHERE rn = 1
-			ORDER BY shipment_id;`)
+// GetShipmentCoordinates accepts the same filters as GetShipments.
+func (h *Handler) GetShipmentCoordinates(c *gin.Context) {
+	filter, ok := shipmentFilter(c)
+	if !ok {
+		return
 	}
 
+	positions, err := h.Events.LatestPositions(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment coordinates"})
 		return
 	}
-	defer rows.Close()
-
-	// Response struct
-	type ShipmentCoordinate struct {
-		ID             int     `json:"id"`
-		TrackingNumber string  `json:"tracking_number"`
-		Lat            float64 `json:"lat"`
-		Lng            float64 `json:"lng"`
+	estimates, err := h.estimates(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment coordinates"})
+		return
 	}
 
-	var shipments []ShipmentCoordinate
-
-	for rows.Next() {
-		var s ShipmentCoordinate
-		if err := rows.Scan(&s.ID, &s.TrackingNumber, &s.Lat, &s.Lng); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse shipment coordinates"})
-			return
-		}
-		shipments = append(shipments, s)
+	type positionETA struct {
+		store.ShipmentPosition
+		*eta.Estimate
 	}
-
-	c.JSON(http.StatusOK, shipments)
-}
-
-func GetShipmentCoordinatesByShipmentID(c *gin.Context) {
-	role := c.GetString("role")
-	id := c.Param("id") // shipment_id from URL
-
-	var rows *sql.Rows
-	var err error
-
-	query := `
-		SELECT 
-			shipment_id AS id, 
-			tracking_number, 
-			lat, 
-			lng, 
-			destination_latitude, 
-			destination_longitude, 
-			warehouse_latitude, 
-			warehouse_longitude
-		FROM (
-			SELECT 
-				se.shipment_id,
-				s.tracking_number,
-				se.latitude AS lat,
-				se.longitude AS lng,
-				s.destination_latitude, 
-				s.destination_longitude,
-				w.latitude AS warehouse_latitude,
-				w.longitude AS warehouse_longitude,
-				ROW_NUMBER() OVER (PARTITION BY se.shipment_id ORDER BY se.timestamp DESC) AS rn
-			FROM shipment_events se
-			LEFT JOIN shipments s ON s.id = se.shipment_id
-			LEFT JOIN warehouses w ON w.id = s.origin_warehouse_id
-		) t
-		WHERE shipment_id = ? AND rn = 1;
-	`
-
-	// Adjust based on role (customers should only see their own shipment)
-	if role == "customer" {
-		userID := c.GetInt("user_id")
-		rows, err = database.DB.Query(query+`
-			AND EXISTS (SELECT 1 FROM shipments WHERE id = ? AND customer_id = ?)`,
-			id, id, userID)
-	} else {
-		rows, err = database.DB.Query(query, id)
+	out := make([]positionETA, len(positions))
+	for i, p := range positions {
+		out[i] = positionETA{p, estimates[p.ShipmentID]}
 	}
+	c.JSON(http.StatusOK, out)
+}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment coordinates"})
+func (h *Handler) GetShipmentCoordinatesByShipmentID(c *gin.Context) {
+	id, ok := shipmentID(c)
+	if !ok {
 		return
 	}
-	defer rows.Close()
 
 	// Response struct
 	type ShipmentCoordinate struct {
-		ID                   int     `json:"id"`
+		ID                   int64   `json:"id"`
 		TrackingNumber       string  `json:"tracking_number"`
 		Lat                  float64 `json:"lat"`
 		Lng                  float64 `json:"lng"`
@@ -343,27 +339,55 @@ func GetShipmentCoordinatesByShipmentID(c *gin.Context) {
 		DestinationLongitude float64 `json:"destination_longitude"`
 		WarehouseLatitude    float64 `json:"warehouse_latitude"`
 		WarehouseLongitude   float64 `json:"warehouse_longitude"`
+		*eta.Estimate
 	}
 
-	var shipments []ShipmentCoordinate
-
-	for rows.Next() {
-		var s ShipmentCoordinate
-		if err := rows.Scan(
-			&s.ID,
-			&s.TrackingNumber,
-			&s.Lat,
-			&s.Lng,
-			&s.DestinationLatitude,
-			&s.DestinationLongitude,
-			&s.WarehouseLatitude,
-			&s.WarehouseLongitude,
-		); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse shipment coordinates"})
-			return
-		}
-		shipments = append(shipments, s)
+	shipments := []ShipmentCoordinate{}
+
+	// Customers should only see their own shipment
+	shipment, err := h.visibleShipment(c, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment coordinates"})
+		return
+	}
+	if shipment == nil {
+		c.JSON(http.StatusOK, shipments)
+		return
 	}
 
-	c.JSON(http.StatusOK, shipments)
+	latest, err := h.Events.Latest(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusOK, shipments)
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment coordinates"})
+		return
+	}
+
+	s := ShipmentCoordinate{
+		ID:             shipment.ID,
+		TrackingNumber: shipment.TrackingNumber,
+		Lat:            *latest.Latitude,
+		Lng:            *latest.Longitude,
+	}
+	if shipment.DestinationLatitude != nil {
+		s.DestinationLatitude = *shipment.DestinationLatitude
+		s.DestinationLongitude = *shipment.DestinationLongitude
+	}
+
+	warehouse, err := h.Warehouses.GetByID(c.Request.Context(), shipment.OriginWarehouseID)
+	if err == nil {
+		s.WarehouseLatitude = warehouse.Latitude
+		s.WarehouseLongitude = warehouse.Longitude
+	} else if !errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment coordinates"})
+		return
+	}
+
+	if s.Estimate, err = h.estimate(c.Request.Context(), shipment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment coordinates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, append(shipments, s))
 }
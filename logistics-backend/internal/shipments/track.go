@@ -0,0 +1,110 @@
+package shipments
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"logistics-backend/internal/store"
+	"logistics-backend/internal/tracking"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Public locations are rounded to two decimal places, roughly a kilometre,
+// so the tracking page never reveals a doorstep.
+const publicLocationScale = 100
+
+type milestone struct {
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type approximateLocation struct {
+	Lat       float64   `json:"lat"`
+	Lng       float64   `json:"lng"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// publicShipment is what anyone holding a tracking number may see. It leaves
+// out customer and driver identity and the destination address.
+type publicShipment struct {
+	TrackingNumber string `json:"tracking_number"`
+	Status         string `json:"status"`
+	OriginHub      string `json:"origin_hub,omitempty"`
+	// DestinationHub is set instead of OriginHub for a shipment collected
+	// from an address, such as a return, which ends at its warehouse.
+	DestinationHub string               `json:"destination_hub,omitempty"`
+	Milestones     []milestone          `json:"milestones"`
+	LastLocation   *approximateLocation `json:"last_location,omitempty"`
+}
+
+func approximate(v float64) float64 {
+	return math.Round(v*publicLocationScale) / publicLocationScale
+}
+
+// TrackShipment is the unauthenticated lookup by tracking number.
+func (h *Handler) TrackShipment(c *gin.Context) {
+	number := tracking.Normalize(c.Param("tracking_number"))
+
+	// Reject typos before touching the database; they can never match.
+	// Numbers of shipments booked before numbers were generated are still
+	// looked up.
+	if err := h.Service.numbers.Format().Trackable(number); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tracking number", "details": err.Error()})
+		return
+	}
+
+	shipment, err := h.Shipments.GetByTrackingNumber(c.Request.Context(), number)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipment not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment"})
+		return
+	}
+
+	events, err := h.Events.List(c.Request.Context(), store.EventFilter{ShipmentID: shipment.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment"})
+		return
+	}
+
+	view := publicShipment{
+		TrackingNumber: shipment.TrackingNumber,
+		Status:         shipment.Status,
+		Milestones:     []milestone{{Status: "created", Timestamp: shipment.CreatedAt}},
+	}
+
+	// Milestones mark the first time the shipment reached each status;
+	// piece events do not count, since one piece arriving is not the
+	// shipment's delivery. Events arrive oldest first, so the last located
+	// one is the latest position.
+	reached := make(map[string]bool)
+	for _, e := range events {
+		if e.PieceID == nil && !reached[e.Status] {
+			reached[e.Status] = true
+			view.Milestones = append(view.Milestones, milestone{Status: e.Status, Timestamp: e.Timestamp})
+		}
+		if e.Latitude != nil && e.Longitude != nil {
+			view.LastLocation = &approximateLocation{
+				Lat:       approximate(*e.Latitude),
+				Lng:       approximate(*e.Longitude),
+				Timestamp: e.Timestamp,
+			}
+		}
+	}
+
+	warehouse, err := h.Warehouses.GetByID(c.Request.Context(), shipment.OriginWarehouseID)
+	if err == nil && shipment.PickupAddress != "" {
+		view.DestinationHub = warehouse.Name
+	} else if err == nil {
+		view.OriginHub = warehouse.Name
+	} else if !errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
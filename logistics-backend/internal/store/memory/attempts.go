@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"logistics-backend/internal/store"
+)
+
+type attemptStore struct {
+	db *DB
+}
+
+func (s *attemptStore) Create(ctx context.Context, a *store.DeliveryAttempt) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, existing := range s.db.attempts {
+		if existing.ShipmentID == a.ShipmentID && existing.Number == a.Number {
+			return store.ErrDuplicate
+		}
+	}
+	a.ID = s.db.id("delivery_attempts")
+	a.AttemptedAt = time.Now().UTC()
+	s.db.attempts[a.ID] = *a
+	return nil
+}
+
+func (s *attemptStore) ListByShipment(ctx context.Context, shipmentID int64) ([]store.DeliveryAttempt, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var attempts []store.DeliveryAttempt
+	for _, a := range s.db.attempts {
+		if a.ShipmentID == shipmentID {
+			attempts = append(attempts, a)
+		}
+	}
+	slices.SortFunc(attempts, func(a, b store.DeliveryAttempt) int { return cmp.Compare(a.Number, b.Number) })
+	return attempts, nil
+}
+
+func (s *attemptStore) Latest(ctx context.Context, f store.ShipmentFilter) ([]store.DeliveryAttempt, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	latest := make(map[int64]store.DeliveryAttempt)
+	for _, a := range s.db.attempts {
+		if sh, ok := s.db.shipments[a.ShipmentID]; !ok || !matchShipment(sh, f) {
+			continue
+		}
+		if cur, ok := latest[a.ShipmentID]; !ok || cur.Number < a.Number {
+			latest[a.ShipmentID] = a
+		}
+	}
+
+	attempts := make([]store.DeliveryAttempt, 0, len(latest))
+	for _, a := range latest {
+		attempts = append(attempts, a)
+	}
+	slices.SortFunc(attempts, func(a, b store.DeliveryAttempt) int { return cmp.Compare(a.ShipmentID, b.ShipmentID) })
+	return attempts, nil
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"logistics-backend/internal/store"
+)
+
+type changeStore struct {
+	db *DB
+}
+
+func (s *changeStore) Create(ctx context.Context, c *store.ShipmentChange) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	c.ID = s.db.id("shipment_changes")
+	if c.ChangedAt.IsZero() {
+		c.ChangedAt = time.Now().UTC()
+	}
+	s.db.changes[c.ID] = *c
+	return nil
+}
+
+func (s *changeStore) List(ctx context.Context, shipmentID int64) ([]store.ShipmentChange, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var changes []store.ShipmentChange
+	for _, c := range s.db.changes {
+		if c.ShipmentID == shipmentID {
+			changes = append(changes, c)
+		}
+	}
+	slices.SortFunc(changes, func(a, b store.ShipmentChange) int { return cmp.Compare(a.ID, b.ID) })
+	return changes, nil
+}
@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"logistics-backend/internal/store"
+)
+
+type eventStore struct {
+	db *DB
+}
+
+func (s *eventStore) Create(ctx context.Context, e *store.ShipmentEvent) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	e.ID = s.db.id("shipment_events")
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	s.db.events[e.ID] = *e
+	return nil
+}
+
+func (s *eventStore) List(ctx context.Context, f store.EventFilter) ([]store.ShipmentEvent, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	after, hasCursor := s.db.events[f.AfterID]
+	var events []store.ShipmentEvent
+	for _, e := range s.db.events {
+		if e.ShipmentID != f.ShipmentID {
+			continue
+		}
+		if f.PieceID != 0 && (e.PieceID == nil || *e.PieceID != f.PieceID) {
+			continue
+		}
+		if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, e.Status) {
+			continue
+		}
+		if !f.From.IsZero() && e.Timestamp.Before(f.From) {
+			continue
+		}
+		if !f.To.IsZero() && !e.Timestamp.Before(f.To) {
+			continue
+		}
+		if f.AfterID != 0 && (!hasCursor || !eventBefore(after, e)) {
+			continue
+		}
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool { return eventBefore(events[i], events[j]) })
+	if f.Limit > 0 && len(events) > f.Limit {
+		events = events[:f.Limit]
+	}
+	return events, nil
+}
+
+// eventBefore orders events by timestamp, then by ID.
+func eventBefore(a, b store.ShipmentEvent) bool {
+	if !a.Timestamp.Equal(b.Timestamp) {
+		return a.Timestamp.Before(b.Timestamp)
+	}
+	return a.ID < b.ID
+}
+
+func (s *eventStore) Latest(ctx context.Context, shipmentID int64) (*store.ShipmentEvent, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	latest, ok := s.db.latestEvents(true)[shipmentID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &latest, nil
+}
+
+func (s *eventStore) LatestPositions(ctx context.Context, f store.ShipmentFilter) ([]store.ShipmentPosition, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var positions []store.ShipmentPosition
+	for shipmentID, e := range s.db.latestEvents(true) {
+		sh, ok := s.db.shipments[shipmentID]
+		if !ok {
+			continue
+		}
+		if !matchShipment(sh, f) {
+			continue
+		}
+		positions = append(positions, store.ShipmentPosition{
+			ShipmentID:     shipmentID,
+			TrackingNumber: sh.TrackingNumber,
+			Lat:            *e.Latitude,
+			Lng:            *e.Longitude,
+			Timestamp:      e.Timestamp,
+		})
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].ShipmentID < positions[j].ShipmentID })
+	return positions, nil
+}
+
+func (s *eventStore) RecentLocated(ctx context.Context, f store.ShipmentFilter, n int) ([]store.ShipmentEvent, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	byShipment := make(map[int64][]store.ShipmentEvent)
+	for _, e := range s.db.events {
+		if e.Latitude == nil || e.Longitude == nil {
+			continue
+		}
+		if sh, ok := s.db.shipments[e.ShipmentID]; ok && matchShipment(sh, f) {
+			byShipment[e.ShipmentID] = append(byShipment[e.ShipmentID], e)
+		}
+	}
+
+	var events []store.ShipmentEvent
+	for _, id := range slices.Sorted(maps.Keys(byShipment)) {
+		recent := byShipment[id]
+		sort.Slice(recent, func(i, j int) bool { return eventBefore(recent[i], recent[j]) })
+		events = append(events, recent[max(0, len(recent)-n):]...)
+	}
+	return events, nil
+}
+
+// latestEvents returns the newest event per shipment, optionally only among
+// events carrying coordinates, breaking timestamp ties by insertion order.
+// Callers must hold the lock.
+func (db *DB) latestEvents(locatedOnly bool) map[int64]store.ShipmentEvent {
+	latest := make(map[int64]store.ShipmentEvent)
+	for _, e := range db.events {
+		if locatedOnly && (e.Latitude == nil || e.Longitude == nil) {
+			continue
+		}
+		cur, ok := latest[e.ShipmentID]
+		if !ok || eventBefore(cur, e) {
+			latest[e.ShipmentID] = e
+		}
+	}
+	return latest
+}
@@ -0,0 +1,152 @@
+// Package memory implements the store interfaces with process-local maps.
+// It is meant for tests and demos; nothing is persisted.
+package memory
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"logistics-backend/internal/store"
+)
+
+// DB holds every table behind a single lock so cross-store reads, such as
+// the latest position of a customer's shipments, see a consistent view.
+type DB struct {
+	mu sync.RWMutex
+	// txMu serialises transactions; see transactor.
+	txMu sync.Mutex
+
+	tables
+}
+
+type tables struct {
+	users      map[int64]store.User
+	warehouses map[int64]store.Warehouse
+	shipments  map[int64]store.Shipment
+	pieces     map[int64]store.Piece
+	events     map[int64]store.ShipmentEvent
+	changes    map[int64]store.ShipmentChange
+	proofs     map[int64]store.DeliveryProof
+	attempts   map[int64]store.DeliveryAttempt
+	returns    map[int64]store.ShipmentReturn
+	vehicles   map[int64]store.Vehicle
+	sequences  map[string]int64
+
+	nextID map[string]int64
+}
+
+func NewDB() *DB {
+	return &DB{tables: tables{
+		users:      make(map[int64]store.User),
+		warehouses: make(map[int64]store.Warehouse),
+		shipments:  make(map[int64]store.Shipment),
+		pieces:     make(map[int64]store.Piece),
+		events:     make(map[int64]store.ShipmentEvent),
+		changes:    make(map[int64]store.ShipmentChange),
+		proofs:     make(map[int64]store.DeliveryProof),
+		attempts:   make(map[int64]store.DeliveryAttempt),
+		returns:    make(map[int64]store.ShipmentReturn),
+		vehicles:   make(map[int64]store.Vehicle),
+		sequences:  make(map[string]int64),
+		nextID:     make(map[string]int64),
+	}}
+}
+
+func (t tables) clone() tables {
+	return tables{
+		users:      maps.Clone(t.users),
+		warehouses: maps.Clone(t.warehouses),
+		shipments:  maps.Clone(t.shipments),
+		pieces:     maps.Clone(t.pieces),
+		events:     maps.Clone(t.events),
+		changes:    maps.Clone(t.changes),
+		proofs:     maps.Clone(t.proofs),
+		attempts:   maps.Clone(t.attempts),
+		returns:    maps.Clone(t.returns),
+		vehicles:   maps.Clone(t.vehicles),
+		sequences:  maps.Clone(t.sequences),
+		nextID:     maps.Clone(t.nextID),
+	}
+}
+
+// New returns a fresh, empty set of in-memory stores.
+func New() *store.Stores {
+	return NewDB().Stores()
+}
+
+func (db *DB) Stores() *store.Stores {
+	stores := db.stores()
+	stores.Tx = &transactor{db: db}
+	return stores
+}
+
+func (db *DB) stores() *store.Stores {
+	return &store.Stores{
+		Users:      &userStore{db: db},
+		Warehouses: &warehouseStore{db: db},
+		Shipments:  &shipmentStore{db: db},
+		Pieces:     &pieceStore{db: db},
+		Events:     &eventStore{db: db},
+		Changes:    &changeStore{db: db},
+		Proofs:     &proofStore{db: db},
+		Attempts:   &attemptStore{db: db},
+		Returns:    &returnStore{db: db},
+		Vehicles:   &vehicleStore{db: db},
+		Sequences:  &sequenceStore{db: db},
+	}
+}
+
+// transactor gives all-or-nothing semantics by snapshotting every table and
+// restoring the snapshot if fn fails. Transactions run one at a time, but
+// writes made outside a transaction are not isolated from one in progress.
+type transactor struct {
+	db *DB
+}
+
+func (t *transactor) WithinTx(ctx context.Context, fn func(tx *store.Stores) error) error {
+	t.db.txMu.Lock()
+	defer t.db.txMu.Unlock()
+
+	t.db.mu.RLock()
+	snapshot := t.db.tables.clone()
+	t.db.mu.RUnlock()
+
+	stores := t.db.stores()
+	stores.Tx = nestedTx{db: t.db, stores: stores}
+	if err := fn(stores); err != nil {
+		t.db.mu.Lock()
+		t.db.tables = snapshot
+		t.db.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// nestedTx joins the transaction that is already running. Like a
+// savepoint, it restores a snapshot of its own if fn fails, leaving the
+// writes made before it in place.
+type nestedTx struct {
+	db     *DB
+	stores *store.Stores
+}
+
+func (n nestedTx) WithinTx(ctx context.Context, fn func(tx *store.Stores) error) error {
+	n.db.mu.RLock()
+	snapshot := n.db.tables.clone()
+	n.db.mu.RUnlock()
+
+	if err := fn(n.stores); err != nil {
+		n.db.mu.Lock()
+		n.db.tables = snapshot
+		n.db.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// id hands out auto-increment keys per table. Callers must hold the write lock.
+func (db *DB) id(table string) int64 {
+	db.nextID[table]++
+	return db.nextID[table]
+}
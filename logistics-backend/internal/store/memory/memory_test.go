@@ -0,0 +1,84 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"logistics-backend/internal/store"
+	"logistics-backend/internal/store/memory"
+)
+
+func TestWithinTxRollsBack(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.New()
+	w := store.Warehouse{Name: "Delhi Hub", Code: "DEL"}
+	if err := stores.Warehouses.Create(ctx, &w); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	err := stores.Tx.WithinTx(ctx, func(tx *store.Stores) error {
+		sh := store.Shipment{TrackingNumber: "LGDEL1", OriginWarehouseID: w.ID, Status: "pending"}
+		if err := tx.Shipments.Create(ctx, &sh); err != nil {
+			return err
+		}
+		if err := tx.Pieces.Create(ctx, &store.Piece{ShipmentID: sh.ID, Number: 1, Barcode: "LGDEL1-001"}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("WithinTx returned %v, want %v", err, failed)
+	}
+	if _, err := stores.Shipments.GetByTrackingNumber(ctx, "LGDEL1"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("shipment survived the rollback: %v", err)
+	}
+	if _, err := stores.Pieces.GetByBarcode(ctx, "LGDEL1-001"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("piece survived the rollback: %v", err)
+	}
+}
+
+func TestDuplicateEmail(t *testing.T) {
+	ctx := context.Background()
+	users := memory.New().Users
+	if err := users.Create(ctx, &store.User{Email: "a@example.com", Role: "driver"}); err != nil {
+		t.Fatal(err)
+	}
+	err := users.Create(ctx, &store.User{Email: "a@example.com", Role: "manager"})
+	if !errors.Is(err, store.ErrDuplicate) {
+		t.Fatalf("second user with the same email: %v, want store.ErrDuplicate", err)
+	}
+}
+
+func TestNestedTxRollsBackAlone(t *testing.T) {
+	ctx := context.Background()
+	stores := memory.New()
+
+	failed := errors.New("failed")
+	err := stores.Tx.WithinTx(ctx, func(tx *store.Stores) error {
+		if err := tx.Warehouses.Create(ctx, &store.Warehouse{Name: "Delhi Hub", Code: "DEL"}); err != nil {
+			return err
+		}
+		err := tx.Tx.WithinTx(ctx, func(tx *store.Stores) error {
+			if err := tx.Warehouses.Create(ctx, &store.Warehouse{Name: "Mumbai Hub", Code: "BOM"}); err != nil {
+				return err
+			}
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Fatalf("nested WithinTx returned %v, want %v", err, failed)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	warehouses, err := stores.Warehouses.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warehouses) != 1 || warehouses[0].Code != "DEL" {
+		t.Fatalf("warehouses %+v, want DEL only", warehouses)
+	}
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"logistics-backend/internal/store"
+)
+
+type pieceStore struct {
+	db *DB
+}
+
+func (s *pieceStore) Create(ctx context.Context, p *store.Piece) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, existing := range s.db.pieces {
+		if existing.Barcode == p.Barcode ||
+			existing.ShipmentID == p.ShipmentID && existing.Number == p.Number {
+			return store.ErrDuplicate
+		}
+	}
+	p.ID = s.db.id("shipment_pieces")
+	p.CreatedAt = time.Now().UTC()
+	s.db.pieces[p.ID] = *p
+	return nil
+}
+
+func (s *pieceStore) GetByBarcode(ctx context.Context, barcode string) (*store.Piece, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, p := range s.db.pieces {
+		if p.Barcode == barcode {
+			return &p, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *pieceStore) ListByShipment(ctx context.Context, shipmentID int64) ([]store.Piece, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var pieces []store.Piece
+	for _, p := range s.db.pieces {
+		if p.ShipmentID == shipmentID {
+			pieces = append(pieces, p)
+		}
+	}
+	slices.SortFunc(pieces, func(a, b store.Piece) int { return cmp.Compare(a.Number, b.Number) })
+	return pieces, nil
+}
+
+func (s *pieceStore) UpdateStatus(ctx context.Context, id int64, from, to string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	p, ok := s.db.pieces[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if p.Status != from {
+		return store.ErrConflict
+	}
+	now := time.Now().UTC()
+	p.Status, p.UpdatedAt = to, &now
+	s.db.pieces[id] = p
+	return nil
+}
+
+func (s *pieceStore) UpdateStatuses(ctx context.Context, shipmentID int64, from []string, to string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	now := time.Now().UTC()
+	for id, p := range s.db.pieces {
+		if p.ShipmentID == shipmentID && slices.Contains(from, p.Status) {
+			p.Status, p.UpdatedAt = to, &now
+			s.db.pieces[id] = p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"logistics-backend/internal/store"
+)
+
+type proofStore struct {
+	db *DB
+}
+
+func (s *proofStore) Create(ctx context.Context, p *store.DeliveryProof) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, existing := range s.db.proofs {
+		if existing.ShipmentID == p.ShipmentID || existing.EventID == p.EventID {
+			return store.ErrDuplicate
+		}
+	}
+	p.ID = s.db.id("delivery_proofs")
+	p.CreatedAt = time.Now().UTC()
+	s.db.proofs[p.ID] = *p
+	return nil
+}
+
+func (s *proofStore) GetByShipment(ctx context.Context, shipmentID int64) (*store.DeliveryProof, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, p := range s.db.proofs {
+		if p.ShipmentID == shipmentID {
+			return &p, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"logistics-backend/internal/store"
+)
+
+type returnStore struct {
+	db *DB
+}
+
+func (s *returnStore) Create(ctx context.Context, r *store.ShipmentReturn) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, existing := range s.db.returns {
+		if existing.OriginalShipmentID == r.OriginalShipmentID || existing.ReturnShipmentID == r.ReturnShipmentID ||
+			existing.RMANumber == r.RMANumber {
+			return store.ErrDuplicate
+		}
+	}
+	r.ID = s.db.id("shipment_returns")
+	r.CreatedAt = time.Now().UTC()
+	s.db.returns[r.ID] = *r
+	return nil
+}
+
+func (s *returnStore) GetByShipment(ctx context.Context, shipmentID int64) (*store.ShipmentReturn, error) {
+	return s.find(func(r store.ShipmentReturn) bool {
+		return r.OriginalShipmentID == shipmentID || r.ReturnShipmentID == shipmentID
+	})
+}
+
+func (s *returnStore) GetByRMA(ctx context.Context, rmaNumber string) (*store.ShipmentReturn, error) {
+	return s.find(func(r store.ShipmentReturn) bool { return r.RMANumber == rmaNumber })
+}
+
+func (s *returnStore) find(match func(store.ShipmentReturn) bool) (*store.ShipmentReturn, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, r := range s.db.returns {
+		if match(r) {
+			return &r, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
@@ -0,0 +1,15 @@
+package memory
+
+import "context"
+
+type sequenceStore struct {
+	db *DB
+}
+
+func (s *sequenceStore) Next(ctx context.Context, name string) (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.sequences[name]++
+	return s.db.sequences[name], nil
+}
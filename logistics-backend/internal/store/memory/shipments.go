@@ -0,0 +1,217 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"logistics-backend/internal/store"
+)
+
+type shipmentStore struct {
+	db *DB
+}
+
+func (s *shipmentStore) Create(ctx context.Context, sh *store.Shipment) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, existing := range s.db.shipments {
+		if existing.TrackingNumber == sh.TrackingNumber {
+			return store.ErrDuplicate
+		}
+	}
+	sh.ID = s.db.id("shipments")
+	sh.Version = 1
+	sh.CreatedAt = time.Now().UTC()
+	s.db.shipments[sh.ID] = *sh
+	return nil
+}
+
+func (s *shipmentStore) GetByID(ctx context.Context, id int64) (*store.Shipment, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	sh, ok := s.db.shipments[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &sh, nil
+}
+
+func (s *shipmentStore) GetByTrackingNumber(ctx context.Context, trackingNumber string) (*store.Shipment, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, sh := range s.db.shipments {
+		if sh.TrackingNumber == trackingNumber {
+			return &sh, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+// matchShipment reports whether sh passes every condition set in f.
+func matchShipment(sh store.Shipment, f store.ShipmentFilter) bool {
+	switch {
+	case f.CustomerID != 0 && sh.CustomerID != f.CustomerID,
+		f.DriverID != 0 && (sh.DriverID == nil || *sh.DriverID != f.DriverID),
+		f.OriginWarehouseID != 0 && sh.OriginWarehouseID != f.OriginWarehouseID,
+		len(f.Statuses) > 0 && !slices.Contains(f.Statuses, sh.Status),
+		!f.CreatedFrom.IsZero() && sh.CreatedAt.Before(f.CreatedFrom),
+		!f.CreatedTo.IsZero() && !sh.CreatedAt.Before(f.CreatedTo),
+		!strings.HasPrefix(sh.TrackingNumber, f.TrackingPrefix),
+		f.NeedsManualPin && !sh.NeedsManualPin:
+		return false
+	}
+	return true
+}
+
+// compareShipments orders a and b by the query's sort key, then by ID.
+func compareShipments(q store.ShipmentQuery, a, b store.Shipment) int {
+	var c int
+	switch q.SortBy {
+	case store.SortByCreatedAt:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	case store.SortByTrackingNumber:
+		c = strings.Compare(a.TrackingNumber, b.TrackingNumber)
+	case store.SortByStatus:
+		c = strings.Compare(a.Status, b.Status)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.ID, b.ID)
+	}
+	if q.Desc {
+		return -c
+	}
+	return c
+}
+
+func (s *shipmentStore) List(ctx context.Context, q store.ShipmentQuery) ([]store.Shipment, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return s.list(q), nil
+}
+
+// list does the work of List. Callers must hold the lock.
+func (s *shipmentStore) list(q store.ShipmentQuery) []store.Shipment {
+	var shipments []store.Shipment
+	for _, sh := range s.db.shipments {
+		if !matchShipment(sh, q.ShipmentFilter) {
+			continue
+		}
+		if q.After != nil && compareShipments(q, sh, *q.After) <= 0 {
+			continue
+		}
+		shipments = append(shipments, sh)
+	}
+	slices.SortFunc(shipments, func(a, b store.Shipment) int { return compareShipments(q, a, b) })
+	if q.Limit > 0 && len(shipments) > q.Limit {
+		shipments = shipments[:q.Limit]
+	}
+	return shipments
+}
+
+// Each builds every summary before calling fn, so fn runs without the lock.
+func (s *shipmentStore) Each(ctx context.Context, q store.ShipmentQuery, fn func(*store.ShipmentSummary) error) error {
+	s.db.mu.RLock()
+	shipments := s.list(q)
+	latest := s.db.latestEvents(false)
+	pieces := make(map[int64]int)
+	for _, p := range s.db.pieces {
+		pieces[p.ShipmentID]++
+	}
+	summaries := make([]store.ShipmentSummary, len(shipments))
+	for i, sh := range shipments {
+		summaries[i].Shipment = sh
+		summaries[i].PieceCount = pieces[sh.ID]
+		if e, ok := latest[sh.ID]; ok {
+			summaries[i].LatestEvent = &e
+		}
+		if sh.DriverID != nil {
+			if u, ok := s.db.users[*sh.DriverID]; ok {
+				summaries[i].Driver = &u
+			}
+		}
+	}
+	s.db.mu.RUnlock()
+
+	for i := range summaries {
+		if err := fn(&summaries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *shipmentStore) Count(ctx context.Context, f store.ShipmentFilter) (int, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	n := 0
+	for _, sh := range s.db.shipments {
+		if matchShipment(sh, f) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *shipmentStore) UpdateStatus(ctx context.Context, id int64, from, to string) error {
+	return s.update(id, func(sh *store.Shipment) error {
+		if sh.Status != from {
+			return store.ErrConflict
+		}
+		sh.Status = to
+		return nil
+	})
+}
+
+func (s *shipmentStore) AssignDriver(ctx context.Context, id, driverID int64) error {
+	return s.update(id, func(sh *store.Shipment) error {
+		sh.DriverID = &driverID
+		return nil
+	})
+}
+
+func (s *shipmentStore) Update(ctx context.Context, sh *store.Shipment, version int64) error {
+	return s.update(sh.ID, func(stored *store.Shipment) error {
+		if stored.Version != version {
+			return store.ErrConflict
+		}
+		stored.OriginWarehouseID = sh.OriginWarehouseID
+		stored.DestinationAddress = sh.DestinationAddress
+		stored.DestinationLatitude = sh.DestinationLatitude
+		stored.DestinationLongitude = sh.DestinationLongitude
+		stored.NeedsManualPin = sh.NeedsManualPin
+		stored.ServiceLevel = sh.ServiceLevel
+		stored.ChargeableWeightKg = sh.ChargeableWeightKg
+		stored.Price = sh.Price
+		stored.Currency = sh.Currency
+		stored.CustomerID = sh.CustomerID
+		sh.Version = version + 1
+		return nil
+	})
+}
+
+// update applies fn to a copy of the shipment and saves it unless fn fails.
+func (s *shipmentStore) update(id int64, fn func(*store.Shipment) error) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	sh, ok := s.db.shipments[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if err := fn(&sh); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	sh.UpdatedAt = &now
+	sh.Version++
+	s.db.shipments[id] = sh
+	return nil
+}
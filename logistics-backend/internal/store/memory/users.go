@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"logistics-backend/internal/store"
+)
+
+type userStore struct {
+	db *DB
+}
+
+func (s *userStore) Create(ctx context.Context, u *store.User) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, existing := range s.db.users {
+		if existing.Email == u.Email {
+			return store.ErrDuplicate
+		}
+	}
+	u.ID = s.db.id("users")
+	s.db.users[u.ID] = *u
+	return nil
+}
+
+func (s *userStore) GetByID(ctx context.Context, id int64) (*store.User, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	u, ok := s.db.users[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &u, nil
+}
+
+func (s *userStore) GetByEmail(ctx context.Context, email string) (*store.User, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, u := range s.db.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *userStore) List(ctx context.Context, role string) ([]store.User, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var users []store.User
+	for _, u := range s.db.users {
+		if role == "" || strings.EqualFold(u.Role, role) {
+			u.PasswordHash = ""
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (s *userStore) ExistsWithRole(ctx context.Context, id int64, role string) (bool, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	u, ok := s.db.users[id]
+	return ok && strings.EqualFold(u.Role, role), nil
+}
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"logistics-backend/internal/store"
+)
+
+type vehicleStore struct {
+	db *DB
+}
+
+func (s *vehicleStore) Create(ctx context.Context, v *store.Vehicle) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	v.ID = s.db.id("vehicles")
+	v.LastUpdate = time.Now().UTC()
+	s.db.vehicles[v.ID] = *v
+	return nil
+}
+
+func (s *vehicleStore) GetByDriverID(ctx context.Context, driverID int64) (*store.Vehicle, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, v := range s.db.vehicles {
+		if v.DriverID == driverID {
+			return &v, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *vehicleStore) List(ctx context.Context) ([]store.Vehicle, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var vehicles []store.Vehicle
+	for _, v := range s.db.vehicles {
+		vehicles = append(vehicles, v)
+	}
+	sort.Slice(vehicles, func(i, j int) bool { return vehicles[i].ID < vehicles[j].ID })
+	return vehicles, nil
+}
+
+func (s *vehicleStore) UpdatePosition(ctx context.Context, id int64, lat, lng float64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	v, ok := s.db.vehicles[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	v.CurrentLat, v.CurrentLng = lat, lng
+	v.LastUpdate = time.Now().UTC()
+	s.db.vehicles[id] = v
+	return nil
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"logistics-backend/internal/store"
+)
+
+type warehouseStore struct {
+	db *DB
+}
+
+func (s *warehouseStore) Create(ctx context.Context, w *store.Warehouse) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if w.Code != "" {
+		for _, existing := range s.db.warehouses {
+			if existing.Code == w.Code {
+				return store.ErrDuplicate
+			}
+		}
+	}
+	w.ID = s.db.id("warehouses")
+	s.db.warehouses[w.ID] = *w
+	return nil
+}
+
+func (s *warehouseStore) GetByID(ctx context.Context, id int64) (*store.Warehouse, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	w, ok := s.db.warehouses[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &w, nil
+}
+
+func (s *warehouseStore) List(ctx context.Context) ([]store.Warehouse, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var warehouses []store.Warehouse
+	for _, w := range s.db.warehouses {
+		warehouses = append(warehouses, w)
+	}
+	sort.Slice(warehouses, func(i, j int) bool { return warehouses[i].ID < warehouses[j].ID })
+	return warehouses, nil
+}
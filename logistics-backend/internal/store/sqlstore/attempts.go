@@ -0,0 +1,99 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"logistics-backend/internal/store"
+)
+
+const attemptColumns = `id, shipment_id, attempt_number, event_id, reason, notes, next_attempt_on,
+	latitude, longitude, actor_id, actor_role, attempted_at`
+
+type attemptStore struct {
+	base
+}
+
+func scanAttempt(row rowScanner) (*store.DeliveryAttempt, error) {
+	var a store.DeliveryAttempt
+	var notes, actorRole sql.NullString
+	var nextAttempt sql.NullTime
+	var lat, lng sql.NullFloat64
+	var actorID sql.NullInt64
+	err := row.Scan(&a.ID, &a.ShipmentID, &a.Number, &a.EventID, &a.Reason, &notes, &nextAttempt,
+		&lat, &lng, &actorID, &actorRole, &a.AttemptedAt)
+	if err != nil {
+		return nil, err
+	}
+	a.Notes, a.ActorRole = notes.String, actorRole.String
+	if nextAttempt.Valid {
+		a.NextAttemptOn = &nextAttempt.Time
+	}
+	if lat.Valid && lng.Valid {
+		a.Latitude, a.Longitude = &lat.Float64, &lng.Float64
+	}
+	if actorID.Valid {
+		a.ActorID = &actorID.Int64
+	}
+	return &a, nil
+}
+
+func (s *attemptStore) Create(ctx context.Context, a *store.DeliveryAttempt) error {
+	var nextAttempt any
+	if a.NextAttemptOn != nil {
+		nextAttempt = s.dialect.Time(*a.NextAttemptOn)
+	}
+	id, err := s.insert(ctx, `
+		INSERT INTO delivery_attempts (shipment_id, attempt_number, event_id, reason, notes, next_attempt_on,
+			latitude, longitude, actor_id, actor_role)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.ShipmentID, a.Number, a.EventID, a.Reason, nullString(a.Notes), nextAttempt,
+		a.Latitude, a.Longitude, a.ActorID, nullString(a.ActorRole),
+	)
+	if err != nil {
+		return s.translate(err)
+	}
+	a.ID = id
+	return nil
+}
+
+func (s *attemptStore) ListByShipment(ctx context.Context, shipmentID int64) ([]store.DeliveryAttempt, error) {
+	return s.list(ctx, `
+		SELECT `+attemptColumns+`
+		FROM delivery_attempts WHERE shipment_id = ? ORDER BY attempt_number`, shipmentID)
+}
+
+func (s *attemptStore) Latest(ctx context.Context, f store.ShipmentFilter) ([]store.DeliveryAttempt, error) {
+	query := `
+		SELECT ` + attemptColumns + `
+		FROM (
+			SELECT ` + qualify(attemptColumns, "da.") + `,
+				ROW_NUMBER() OVER (PARTITION BY da.shipment_id ORDER BY da.attempt_number DESC) AS rn
+			FROM delivery_attempts da
+			JOIN shipments s ON s.id = da.shipment_id`
+
+	conds, args := s.shipmentConditions(f, "s.")
+	query += where(conds) + `
+		) t
+		WHERE rn = 1
+		ORDER BY shipment_id`
+	return s.list(ctx, query, args...)
+}
+
+func (s *attemptStore) list(ctx context.Context, query string, args ...any) ([]store.DeliveryAttempt, error) {
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []store.DeliveryAttempt
+	for rows.Next() {
+		a, err := scanAttempt(rows)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, *a)
+	}
+	return attempts, rows.Err()
+}
@@ -0,0 +1,57 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"logistics-backend/internal/store"
+)
+
+type changeStore struct {
+	base
+}
+
+func (s *changeStore) Create(ctx context.Context, c *store.ShipmentChange) error {
+	id, err := s.insert(ctx, `
+		INSERT INTO shipment_changes (shipment_id, version, field, old_value, new_value, actor_id, actor_role)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		c.ShipmentID, c.Version, c.Field, c.OldValue, c.NewValue, c.ActorID, nullString(c.ActorRole),
+	)
+	if err != nil {
+		return s.translate(err)
+	}
+	c.ID = id
+	return nil
+}
+
+func (s *changeStore) List(ctx context.Context, shipmentID int64) ([]store.ShipmentChange, error) {
+	rows, err := s.query(ctx, `
+		SELECT id, shipment_id, version, field, old_value, new_value, actor_id, actor_role, changed_at
+		FROM shipment_changes WHERE shipment_id = ? ORDER BY id`, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []store.ShipmentChange
+	for rows.Next() {
+		var c store.ShipmentChange
+		var oldValue, newValue, actorRole sql.NullString
+		var actorID sql.NullInt64
+		if err := rows.Scan(&c.ID, &c.ShipmentID, &c.Version, &c.Field, &oldValue, &newValue, &actorID, &actorRole, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		if oldValue.Valid {
+			c.OldValue = &oldValue.String
+		}
+		if newValue.Valid {
+			c.NewValue = &newValue.String
+		}
+		if actorID.Valid {
+			c.ActorID = &actorID.Int64
+		}
+		c.ActorRole = actorRole.String
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
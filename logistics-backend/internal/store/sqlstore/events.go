@@ -0,0 +1,212 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"logistics-backend/internal/store"
+)
+
+type eventStore struct {
+	base
+}
+
+const eventColumns = `id, shipment_id, piece_id, status, latitude, longitude, actor_id, actor_role, reason,
+	scan_type, scanned_at, timestamp`
+
+func scanEvent(row rowScanner) (*store.ShipmentEvent, error) {
+	var e store.ShipmentEvent
+	var lat, lng sql.NullFloat64
+	var pieceID, actorID sql.NullInt64
+	var actorRole, reason, scanType sql.NullString
+	var scannedAt sql.NullTime
+	err := row.Scan(&e.ID, &e.ShipmentID, &pieceID, &e.Status, &lat, &lng, &actorID, &actorRole, &reason,
+		&scanType, &scannedAt, &e.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if lat.Valid && lng.Valid {
+		e.Latitude, e.Longitude = &lat.Float64, &lng.Float64
+	}
+	if pieceID.Valid {
+		e.PieceID = &pieceID.Int64
+	}
+	if actorID.Valid {
+		e.ActorID = &actorID.Int64
+	}
+	e.ActorRole = actorRole.String
+	e.Reason = reason.String
+	e.ScanType = scanType.String
+	if scannedAt.Valid {
+		e.ScannedAt = &scannedAt.Time
+	}
+	return &e, nil
+}
+
+// nullString stores empty strings as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func (s *eventStore) Create(ctx context.Context, e *store.ShipmentEvent) error {
+	var scannedAt any
+	if e.ScannedAt != nil {
+		scannedAt = s.dialect.Time(*e.ScannedAt)
+	}
+	id, err := s.insert(ctx, `
+		INSERT INTO shipment_events (shipment_id, piece_id, status, latitude, longitude, actor_id, actor_role, reason,
+			scan_type, scanned_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ShipmentID, e.PieceID, e.Status, e.Latitude, e.Longitude, e.ActorID, nullString(e.ActorRole), nullString(e.Reason),
+		nullString(e.ScanType), scannedAt,
+	)
+	if err != nil {
+		return s.translate(err)
+	}
+	e.ID = id
+	return nil
+}
+
+func (s *eventStore) List(ctx context.Context, f store.EventFilter) ([]store.ShipmentEvent, error) {
+	query := `SELECT ` + eventColumns + ` FROM shipment_events WHERE shipment_id = ?`
+	args := []any{f.ShipmentID}
+
+	if f.PieceID != 0 {
+		query += ` AND piece_id = ?`
+		args = append(args, f.PieceID)
+	}
+	if len(f.Statuses) > 0 {
+		query += ` AND status IN (?` + strings.Repeat(`, ?`, len(f.Statuses)-1) + `)`
+		args = append(args, anySlice(f.Statuses)...)
+	}
+	if !f.From.IsZero() {
+		query += ` AND timestamp >= ?`
+		args = append(args, s.dialect.Time(f.From))
+	}
+	if !f.To.IsZero() {
+		query += ` AND timestamp < ?`
+		args = append(args, s.dialect.Time(f.To))
+	}
+	if f.AfterID != 0 {
+		// Look the cursor's timestamp up rather than trusting the client with
+		// it, so the cursor stays a single opaque ID.
+		query += `
+			AND (timestamp > (SELECT timestamp FROM shipment_events WHERE id = ?)
+				OR (timestamp = (SELECT timestamp FROM shipment_events WHERE id = ?) AND id > ?))`
+		args = append(args, f.AfterID, f.AfterID, f.AfterID)
+	}
+	query += ` ORDER BY timestamp, id`
+	if f.Limit > 0 {
+		query += ` LIMIT ` + strconv.Itoa(f.Limit)
+	}
+
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []store.ShipmentEvent
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, rows.Err()
+}
+
+func (s *eventStore) Latest(ctx context.Context, shipmentID int64) (*store.ShipmentEvent, error) {
+	e, err := scanEvent(s.queryRow(ctx, `
+		SELECT `+eventColumns+`
+		FROM shipment_events
+		WHERE shipment_id = ? AND latitude IS NOT NULL AND longitude IS NOT NULL
+		ORDER BY timestamp DESC, id DESC
+		LIMIT 1`, shipmentID,
+	))
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	return e, nil
+}
+
+func (s *eventStore) LatestPositions(ctx context.Context, f store.ShipmentFilter) ([]store.ShipmentPosition, error) {
+	query := `
+		SELECT shipment_id AS id, tracking_number, lat, lng, ts
+		FROM (
+			SELECT
+				se.shipment_id,
+				s.tracking_number,
+				se.latitude AS lat,
+				se.longitude AS lng,
+				se.timestamp AS ts,
+				ROW_NUMBER() OVER (PARTITION BY se.shipment_id ORDER BY se.timestamp DESC, se.id DESC) AS rn
+			FROM shipment_events se
+			JOIN shipments s ON s.id = se.shipment_id
+			WHERE se.latitude IS NOT NULL AND se.longitude IS NOT NULL`
+
+	conds, args := s.shipmentConditions(f, "s.")
+	for _, cond := range conds {
+		query += ` AND ` + cond
+	}
+	query += `
+		) t
+		WHERE rn = 1
+		ORDER BY shipment_id`
+
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var positions []store.ShipmentPosition
+	for rows.Next() {
+		var p store.ShipmentPosition
+		if err := rows.Scan(&p.ShipmentID, &p.TrackingNumber, &p.Lat, &p.Lng, &p.Timestamp); err != nil {
+			return nil, err
+		}
+		positions = append(positions, p)
+	}
+	return positions, rows.Err()
+}
+
+func (s *eventStore) RecentLocated(ctx context.Context, f store.ShipmentFilter, n int) ([]store.ShipmentEvent, error) {
+	query := `
+		SELECT ` + eventColumns + `
+		FROM (
+			SELECT ` + qualify(eventColumns, "se.") + `,
+				ROW_NUMBER() OVER (PARTITION BY se.shipment_id ORDER BY se.timestamp DESC, se.id DESC) AS rn
+			FROM shipment_events se
+			JOIN shipments s ON s.id = se.shipment_id
+			WHERE se.latitude IS NOT NULL AND se.longitude IS NOT NULL`
+
+	conds, args := s.shipmentConditions(f, "s.")
+	for _, cond := range conds {
+		query += ` AND ` + cond
+	}
+	query += `
+		) t
+		WHERE rn <= ?
+		ORDER BY shipment_id, timestamp, id`
+	args = append(args, n)
+
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []store.ShipmentEvent
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,113 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"logistics-backend/internal/store"
+)
+
+const pieceColumns = `id, shipment_id, piece_number, barcode, description, weight_kg,
+	length_cm, width_cm, height_cm, declared_value, status, created_at, updated_at`
+
+type pieceStore struct {
+	base
+}
+
+func scanPiece(row rowScanner) (*store.Piece, error) {
+	var p store.Piece
+	var description sql.NullString
+	var weight, length, width, height, value sql.NullFloat64
+	var updatedAt sql.NullTime
+	err := row.Scan(&p.ID, &p.ShipmentID, &p.Number, &p.Barcode, &description, &weight,
+		&length, &width, &height, &value, &p.Status, &p.CreatedAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	optional := func(v sql.NullFloat64) *float64 {
+		if !v.Valid {
+			return nil
+		}
+		return &v.Float64
+	}
+	p.Description = description.String
+	p.WeightKg = optional(weight)
+	p.LengthCm, p.WidthCm, p.HeightCm = optional(length), optional(width), optional(height)
+	p.DeclaredValue = optional(value)
+	if updatedAt.Valid {
+		p.UpdatedAt = &updatedAt.Time
+	}
+	return &p, nil
+}
+
+func (s *pieceStore) Create(ctx context.Context, p *store.Piece) error {
+	id, err := s.insert(ctx, `
+		INSERT INTO shipment_pieces (shipment_id, piece_number, barcode, description, weight_kg,
+			length_cm, width_cm, height_cm, declared_value, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ShipmentID, p.Number, p.Barcode, nullString(p.Description), p.WeightKg,
+		p.LengthCm, p.WidthCm, p.HeightCm, p.DeclaredValue, p.Status,
+	)
+	if err != nil {
+		return s.translate(err)
+	}
+	p.ID = id
+	return nil
+}
+
+func (s *pieceStore) GetByBarcode(ctx context.Context, barcode string) (*store.Piece, error) {
+	p, err := scanPiece(s.queryRow(ctx,
+		`SELECT `+pieceColumns+` FROM shipment_pieces WHERE barcode = ?`, barcode))
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	return p, nil
+}
+
+func (s *pieceStore) ListByShipment(ctx context.Context, shipmentID int64) ([]store.Piece, error) {
+	rows, err := s.query(ctx,
+		`SELECT `+pieceColumns+` FROM shipment_pieces WHERE shipment_id = ? ORDER BY piece_number`, shipmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pieces []store.Piece
+	for rows.Next() {
+		p, err := scanPiece(rows)
+		if err != nil {
+			return nil, err
+		}
+		pieces = append(pieces, *p)
+	}
+	return pieces, rows.Err()
+}
+
+func (s *pieceStore) UpdateStatus(ctx context.Context, id int64, from, to string) error {
+	res, err := s.exec(ctx,
+		`UPDATE shipment_pieces SET status = ?, updated_at = `+s.dialect.Now()+` WHERE id = ? AND status = ?`,
+		to, id, from)
+	if err != nil {
+		return err
+	}
+	if err := checkAffected(res); err != nil {
+		var exists bool
+		if s.queryRow(ctx, `SELECT EXISTS(SELECT 1 FROM shipment_pieces WHERE id = ?)`, id).Scan(&exists) == nil && exists {
+			return store.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *pieceStore) UpdateStatuses(ctx context.Context, shipmentID int64, from []string, to string) error {
+	if len(from) == 0 {
+		return nil
+	}
+	_, err := s.exec(ctx, `
+		UPDATE shipment_pieces SET status = ?, updated_at = `+s.dialect.Now()+`
+		WHERE shipment_id = ? AND status IN (?`+strings.Repeat(`, ?`, len(from)-1)+`)`,
+		append([]any{to, shipmentID}, anySlice(from)...)...)
+	return err
+}
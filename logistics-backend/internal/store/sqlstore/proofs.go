@@ -0,0 +1,43 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"logistics-backend/internal/store"
+)
+
+type proofStore struct {
+	base
+}
+
+func (s *proofStore) Create(ctx context.Context, p *store.DeliveryProof) error {
+	id, err := s.insert(ctx, `
+		INSERT INTO delivery_proofs (shipment_id, event_id, recipient_name, signature_key, signature_type,
+			photo_key, photo_type, latitude, longitude)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ShipmentID, p.EventID, p.RecipientName, p.SignatureKey, p.SignatureType,
+		nullString(p.PhotoKey), nullString(p.PhotoType), p.Latitude, p.Longitude,
+	)
+	if err != nil {
+		return s.translate(err)
+	}
+	p.ID = id
+	return nil
+}
+
+func (s *proofStore) GetByShipment(ctx context.Context, shipmentID int64) (*store.DeliveryProof, error) {
+	var p store.DeliveryProof
+	var photoKey, photoType sql.NullString
+	err := s.queryRow(ctx, `
+		SELECT id, shipment_id, event_id, recipient_name, signature_key, signature_type,
+			photo_key, photo_type, latitude, longitude, created_at
+		FROM delivery_proofs WHERE shipment_id = ?`, shipmentID,
+	).Scan(&p.ID, &p.ShipmentID, &p.EventID, &p.RecipientName, &p.SignatureKey, &p.SignatureType,
+		&photoKey, &photoType, &p.Latitude, &p.Longitude, &p.CreatedAt)
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	p.PhotoKey, p.PhotoType = photoKey.String, photoType.String
+	return &p, nil
+}
@@ -0,0 +1,70 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"logistics-backend/internal/store"
+)
+
+const returnColumns = `id, rma_number, original_shipment_id, return_shipment_id, reason, notes,
+	pickup_address, pickup_latitude, pickup_longitude, requested_by, requested_role, created_at`
+
+type returnStore struct {
+	base
+}
+
+func scanReturn(row rowScanner) (*store.ShipmentReturn, error) {
+	var r store.ShipmentReturn
+	var notes, requestedRole sql.NullString
+	var lat, lng sql.NullFloat64
+	var requestedBy sql.NullInt64
+	err := row.Scan(&r.ID, &r.RMANumber, &r.OriginalShipmentID, &r.ReturnShipmentID, &r.Reason, &notes,
+		&r.PickupAddress, &lat, &lng, &requestedBy, &requestedRole, &r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	r.Notes, r.RequestedRole = notes.String, requestedRole.String
+	if lat.Valid && lng.Valid {
+		r.PickupLatitude, r.PickupLongitude = &lat.Float64, &lng.Float64
+	}
+	if requestedBy.Valid {
+		r.RequestedBy = &requestedBy.Int64
+	}
+	return &r, nil
+}
+
+func (s *returnStore) Create(ctx context.Context, r *store.ShipmentReturn) error {
+	id, err := s.insert(ctx, `
+		INSERT INTO shipment_returns (rma_number, original_shipment_id, return_shipment_id, reason, notes,
+			pickup_address, pickup_latitude, pickup_longitude, requested_by, requested_role)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.RMANumber, r.OriginalShipmentID, r.ReturnShipmentID, r.Reason, nullString(r.Notes),
+		r.PickupAddress, r.PickupLatitude, r.PickupLongitude, r.RequestedBy, nullString(r.RequestedRole),
+	)
+	if err != nil {
+		return s.translate(err)
+	}
+	r.ID = id
+	return nil
+}
+
+func (s *returnStore) GetByShipment(ctx context.Context, shipmentID int64) (*store.ShipmentReturn, error) {
+	r, err := scanReturn(s.queryRow(ctx, `
+		SELECT `+returnColumns+`
+		FROM shipment_returns WHERE original_shipment_id = ? OR return_shipment_id = ?`, shipmentID, shipmentID))
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	return r, nil
+}
+
+func (s *returnStore) GetByRMA(ctx context.Context, rmaNumber string) (*store.ShipmentReturn, error) {
+	r, err := scanReturn(s.queryRow(ctx, `
+		SELECT `+returnColumns+`
+		FROM shipment_returns WHERE rma_number = ?`, rmaNumber))
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	return r, nil
+}
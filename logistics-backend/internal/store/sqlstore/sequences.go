@@ -0,0 +1,35 @@
+package sqlstore
+
+import (
+	"context"
+
+	"logistics-backend/internal/database"
+)
+
+type sequenceStore struct {
+	base
+}
+
+// Next bumps the counter with a single upsert, so concurrent callers never
+// see the same value even outside a transaction.
+func (s *sequenceStore) Next(ctx context.Context, name string) (int64, error) {
+	if s.dialect == database.MySQL {
+		// LAST_INSERT_ID(expr) makes the new value available through
+		// LastInsertId, since MySQL has no RETURNING clause.
+		res, err := s.exec(ctx, `
+			INSERT INTO sequences (name, last_value) VALUES (?, LAST_INSERT_ID(1))
+			ON DUPLICATE KEY UPDATE last_value = LAST_INSERT_ID(last_value + 1)`, name)
+		if err != nil {
+			return 0, err
+		}
+		return res.LastInsertId()
+	}
+
+	var value int64
+	err := s.queryRow(ctx, `
+		INSERT INTO sequences (name, last_value) VALUES (?, 1)
+		ON CONFLICT (name) DO UPDATE SET last_value = sequences.last_value + 1
+		RETURNING last_value`, name,
+	).Scan(&value)
+	return value, err
+}
@@ -0,0 +1,373 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"logistics-backend/internal/store"
+)
+
+const shipmentColumns = `id, tracking_number, origin_warehouse_id,
+	pickup_address, pickup_latitude, pickup_longitude, destination_address,
+	destination_latitude, destination_longitude, needs_manual_pin,
+	service_level, chargeable_weight_kg, price, currency, customer_id, driver_id, status, version,
+	created_at, updated_at`
+
+type shipmentStore struct {
+	base
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanShipment(row rowScanner) (*store.Shipment, error) {
+	var s store.Shipment
+	var pickupLat, pickupLng, destLat, destLng, chargeable, price sql.NullFloat64
+	var pickup, serviceLevel, currency sql.NullString
+	var driverID sql.NullInt64
+	var updatedAt sql.NullTime
+	err := row.Scan(
+		&s.ID,
+		&s.TrackingNumber,
+		&s.OriginWarehouseID,
+		&pickup,
+		&pickupLat,
+		&pickupLng,
+		&s.DestinationAddress,
+		&destLat,
+		&destLng,
+		&s.NeedsManualPin,
+		&serviceLevel,
+		&chargeable,
+		&price,
+		&currency,
+		&s.CustomerID,
+		&driverID,
+		&s.Status,
+		&s.Version,
+		&s.CreatedAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.PickupAddress = pickup.String
+	if pickupLat.Valid && pickupLng.Valid {
+		s.PickupLatitude = &pickupLat.Float64
+		s.PickupLongitude = &pickupLng.Float64
+	}
+	if destLat.Valid && destLng.Valid {
+		s.DestinationLatitude = &destLat.Float64
+		s.DestinationLongitude = &destLng.Float64
+	}
+	s.ServiceLevel, s.Currency = serviceLevel.String, currency.String
+	if chargeable.Valid {
+		s.ChargeableWeightKg = &chargeable.Float64
+	}
+	if price.Valid {
+		s.Price = &price.Float64
+	}
+	if driverID.Valid {
+		s.DriverID = &driverID.Int64
+	}
+	if updatedAt.Valid {
+		s.UpdatedAt = &updatedAt.Time
+	}
+	return &s, nil
+}
+
+func (s *shipmentStore) Create(ctx context.Context, sh *store.Shipment) error {
+	id, err := s.insert(ctx, `
+		INSERT INTO shipments (tracking_number, origin_warehouse_id,
+			pickup_address, pickup_latitude, pickup_longitude, destination_address,
+			destination_latitude, destination_longitude, needs_manual_pin,
+			service_level, chargeable_weight_kg, price, currency, customer_id, driver_id, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		sh.TrackingNumber, sh.OriginWarehouseID,
+		nullString(sh.PickupAddress), sh.PickupLatitude, sh.PickupLongitude, sh.DestinationAddress,
+		sh.DestinationLatitude, sh.DestinationLongitude, sh.NeedsManualPin,
+		nullString(sh.ServiceLevel), sh.ChargeableWeightKg, sh.Price, nullString(sh.Currency), sh.CustomerID, sh.DriverID, sh.Status,
+	)
+	if err != nil {
+		return s.translate(err)
+	}
+	sh.ID = id
+	sh.Version = 1
+	return nil
+}
+
+func (s *shipmentStore) GetByID(ctx context.Context, id int64) (*store.Shipment, error) {
+	sh, err := scanShipment(s.queryRow(ctx,
+		`SELECT `+shipmentColumns+` FROM shipments WHERE id = ?`, id))
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	return sh, nil
+}
+
+func (s *shipmentStore) GetByTrackingNumber(ctx context.Context, trackingNumber string) (*store.Shipment, error) {
+	sh, err := scanShipment(s.queryRow(ctx,
+		`SELECT `+shipmentColumns+` FROM shipments WHERE tracking_number = ?`, trackingNumber))
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	return sh, nil
+}
+
+// shipmentConditions renders f as SQL conditions on the shipments table,
+// whose columns are qualified with prefix (such as "s.") when it is joined.
+func (b base) shipmentConditions(f store.ShipmentFilter, prefix string) ([]string, []any) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg ...any) {
+		conds = append(conds, prefix+cond)
+		args = append(args, arg...)
+	}
+
+	if f.CustomerID != 0 {
+		add(`customer_id = ?`, f.CustomerID)
+	}
+	if f.DriverID != 0 {
+		add(`driver_id = ?`, f.DriverID)
+	}
+	if f.OriginWarehouseID != 0 {
+		add(`origin_warehouse_id = ?`, f.OriginWarehouseID)
+	}
+	if len(f.Statuses) > 0 {
+		add(`status IN (?`+strings.Repeat(`, ?`, len(f.Statuses)-1)+`)`, anySlice(f.Statuses)...)
+	}
+	if !f.CreatedFrom.IsZero() {
+		add(`created_at >= ?`, b.dialect.Time(f.CreatedFrom))
+	}
+	if !f.CreatedTo.IsZero() {
+		add(`created_at < ?`, b.dialect.Time(f.CreatedTo))
+	}
+	if f.TrackingPrefix != "" {
+		// The prefix holds no LIKE wildcards, see store.ShipmentFilter.
+		add(`tracking_number LIKE ?`, f.TrackingPrefix+"%")
+	}
+	if f.NeedsManualPin {
+		add(`needs_manual_pin = ?`, true)
+	}
+	return conds, args
+}
+
+func where(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return ` WHERE ` + strings.Join(conds, ` AND `)
+}
+
+func anySlice[T any](values []T) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+// sortKey returns the column a listing is sorted by and the value of that
+// column for the shipment a page continues after.
+func (s *shipmentStore) sortKey(q store.ShipmentQuery) (string, any) {
+	var after store.Shipment
+	if q.After != nil {
+		after = *q.After
+	}
+	switch q.SortBy {
+	case store.SortByCreatedAt:
+		return "created_at", s.dialect.Time(after.CreatedAt)
+	case store.SortByTrackingNumber:
+		return "tracking_number", after.TrackingNumber
+	case store.SortByStatus:
+		return "status", after.Status
+	}
+	return "id", after.ID
+}
+
+// queryClauses renders the WHERE, ORDER BY and LIMIT clauses of q, with
+// shipment columns qualified by prefix.
+func (s *shipmentStore) queryClauses(q store.ShipmentQuery, prefix string) (string, []any) {
+	conds, args := s.shipmentConditions(q.ShipmentFilter, prefix)
+
+	column, value := s.sortKey(q)
+	id := prefix + "id"
+	column = prefix + column
+	dir, cmp := "ASC", ">"
+	if q.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	if q.After != nil {
+		if column == id {
+			conds = append(conds, id+` `+cmp+` ?`)
+			args = append(args, q.After.ID)
+		} else {
+			conds = append(conds, `(`+column+` `+cmp+` ? OR (`+column+` = ? AND `+id+` `+cmp+` ?))`)
+			args = append(args, value, value, q.After.ID)
+		}
+	}
+
+	clauses := where(conds)
+	if column == id {
+		clauses += ` ORDER BY ` + id + ` ` + dir
+	} else {
+		clauses += ` ORDER BY ` + column + ` ` + dir + `, ` + id + ` ` + dir
+	}
+	if q.Limit > 0 {
+		clauses += ` LIMIT ` + strconv.Itoa(q.Limit)
+	}
+	return clauses, args
+}
+
+func (s *shipmentStore) List(ctx context.Context, q store.ShipmentQuery) ([]store.Shipment, error) {
+	clauses, args := s.queryClauses(q, "")
+	rows, err := s.query(ctx, `SELECT `+shipmentColumns+` FROM shipments`+clauses, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shipments []store.Shipment
+	for rows.Next() {
+		sh, err := scanShipment(rows)
+		if err != nil {
+			return nil, err
+		}
+		shipments = append(shipments, *sh)
+	}
+	return shipments, rows.Err()
+}
+
+// withExtra scans columns following the ones a scan function reads.
+type withExtra struct {
+	rowScanner
+	extra []any
+}
+
+func (w withExtra) Scan(dest ...any) error {
+	return w.rowScanner.Scan(append(dest, w.extra...)...)
+}
+
+// qualify prefixes every column of a comma-separated list.
+func qualify(columns, prefix string) string {
+	parts := strings.Split(columns, ",")
+	for i, p := range parts {
+		parts[i] = prefix + strings.TrimSpace(p)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (s *shipmentStore) Each(ctx context.Context, q store.ShipmentQuery, fn func(*store.ShipmentSummary) error) error {
+	clauses, args := s.queryClauses(q, "s.")
+	rows, err := s.query(ctx, `
+		SELECT `+qualify(shipmentColumns, "s.")+`,
+			e.id, e.status, e.latitude, e.longitude, e.timestamp,
+			u.id, u.name, u.email,
+			(SELECT COUNT(*) FROM shipment_pieces p WHERE p.shipment_id = s.id)
+		FROM shipments s
+		LEFT JOIN (
+			SELECT id, shipment_id, status, latitude, longitude, timestamp,
+				ROW_NUMBER() OVER (PARTITION BY shipment_id ORDER BY timestamp DESC, id DESC) AS rn
+			FROM shipment_events
+		) e ON e.shipment_id = s.id AND e.rn = 1
+		LEFT JOIN users u ON u.id = s.driver_id`+clauses, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventID, driverID sql.NullInt64
+		var eventStatus, driverName, driverEmail sql.NullString
+		var eventLat, eventLng sql.NullFloat64
+		var eventTime sql.NullTime
+		var pieces int
+		sh, err := scanShipment(withExtra{rows, []any{
+			&eventID, &eventStatus, &eventLat, &eventLng, &eventTime,
+			&driverID, &driverName, &driverEmail, &pieces,
+		}})
+		if err != nil {
+			return err
+		}
+
+		summary := &store.ShipmentSummary{Shipment: *sh, PieceCount: pieces}
+		if eventID.Valid {
+			e := &store.ShipmentEvent{
+				ID:         eventID.Int64,
+				ShipmentID: sh.ID,
+				Status:     eventStatus.String,
+				Timestamp:  eventTime.Time,
+			}
+			if eventLat.Valid && eventLng.Valid {
+				e.Latitude, e.Longitude = &eventLat.Float64, &eventLng.Float64
+			}
+			summary.LatestEvent = e
+		}
+		if driverID.Valid {
+			summary.Driver = &store.User{ID: driverID.Int64, Name: driverName.String, Email: driverEmail.String, Role: "driver"}
+		}
+		if err := fn(summary); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *shipmentStore) Count(ctx context.Context, f store.ShipmentFilter) (int, error) {
+	conds, args := s.shipmentConditions(f, "")
+	var n int
+	err := s.queryRow(ctx, `SELECT COUNT(*) FROM shipments`+where(conds), args...).Scan(&n)
+	return n, err
+}
+
+func (s *shipmentStore) UpdateStatus(ctx context.Context, id int64, from, to string) error {
+	res, err := s.exec(ctx,
+		`UPDATE shipments SET status = ?, version = version + 1, updated_at = `+s.dialect.Now()+` WHERE id = ? AND status = ?`,
+		to, id, from)
+	if err != nil {
+		return err
+	}
+	if err := checkAffected(res); err != nil {
+		// Tell a missing shipment apart from one whose status moved on.
+		if _, getErr := s.GetByID(ctx, id); getErr == nil {
+			return store.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *shipmentStore) AssignDriver(ctx context.Context, id, driverID int64) error {
+	res, err := s.exec(ctx, `UPDATE shipments SET driver_id = ?, version = version + 1, updated_at = `+s.dialect.Now()+` WHERE id = ?`, driverID, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *shipmentStore) Update(ctx context.Context, sh *store.Shipment, version int64) error {
+	res, err := s.exec(ctx, `
+		UPDATE shipments SET origin_warehouse_id = ?, destination_address = ?,
+			destination_latitude = ?, destination_longitude = ?, needs_manual_pin = ?,
+			service_level = ?, chargeable_weight_kg = ?, price = ?, currency = ?, customer_id = ?,
+			version = version + 1, updated_at = `+s.dialect.Now()+`
+		WHERE id = ? AND version = ?`,
+		sh.OriginWarehouseID, sh.DestinationAddress,
+		sh.DestinationLatitude, sh.DestinationLongitude, sh.NeedsManualPin,
+		nullString(sh.ServiceLevel), sh.ChargeableWeightKg, sh.Price, nullString(sh.Currency), sh.CustomerID,
+		sh.ID, version)
+	if err != nil {
+		return s.translate(err)
+	}
+	if err := checkAffected(res); err != nil {
+		if _, getErr := s.GetByID(ctx, sh.ID); getErr == nil {
+			return store.ErrConflict
+		}
+		return err
+	}
+	sh.Version = version + 1
+	return nil
+}
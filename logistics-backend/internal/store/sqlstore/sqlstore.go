@@ -0,0 +1,147 @@
+// Package sqlstore implements the store interfaces on top of database/sql.
+// SQL that differs between backends is delegated to database.Dialect.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"logistics-backend/internal/database"
+	"logistics-backend/internal/store"
+)
+
+func New(db *sql.DB, dialect database.Dialect) *store.Stores {
+	stores := newStores(base{db: db, dialect: dialect})
+	stores.Tx = &transactor{db: db, dialect: dialect}
+	return stores
+}
+
+func newStores(b base) *store.Stores {
+	return &store.Stores{
+		Users:      &userStore{b},
+		Warehouses: &warehouseStore{b},
+		Shipments:  &shipmentStore{b},
+		Pieces:     &pieceStore{b},
+		Events:     &eventStore{b},
+		Changes:    &changeStore{b},
+		Proofs:     &proofStore{b},
+		Attempts:   &attemptStore{b},
+		Returns:    &returnStore{b},
+		Vehicles:   &vehicleStore{b},
+		Sequences:  &sequenceStore{b},
+	}
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// base is embedded by every store for access to the connection pool, or the
+// open transaction, and its dialect.
+type base struct {
+	db      querier
+	dialect database.Dialect
+}
+
+type transactor struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+func (t *transactor) WithinTx(ctx context.Context, fn func(tx *store.Stores) error) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stores := newStores(base{db: tx, dialect: t.dialect})
+	stores.Tx = nestedTx{tx: tx, dialect: t.dialect, depth: 1}
+	if err := fn(stores); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// nestedTx joins the transaction that is already open under a savepoint,
+// so that fn failing undoes its own writes and leaves the transaction
+// usable. Savepoints are named by depth: MySQL replaces a savepoint of the
+// same name rather than nesting it.
+type nestedTx struct {
+	tx      *sql.Tx
+	dialect database.Dialect
+	depth   int
+}
+
+func (n nestedTx) WithinTx(ctx context.Context, fn func(tx *store.Stores) error) error {
+	name := "nested_" + strconv.Itoa(n.depth)
+	if _, err := n.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	stores := newStores(base{db: n.tx, dialect: n.dialect})
+	stores.Tx = nestedTx{tx: n.tx, dialect: n.dialect, depth: n.depth + 1}
+	if err := fn(stores); err != nil {
+		if _, rbErr := n.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	_, err := n.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+func (b base) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return b.db.ExecContext(ctx, b.dialect.Rebind(query), args...)
+}
+
+func (b base) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return b.db.QueryContext(ctx, b.dialect.Rebind(query), args...)
+}
+
+func (b base) queryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return b.db.QueryRowContext(ctx, b.dialect.Rebind(query), args...)
+}
+
+// insert runs an INSERT and returns the generated id, using RETURNING on
+// backends without LastInsertId.
+func (b base) insert(ctx context.Context, query string, args ...any) (int64, error) {
+	if !b.dialect.SupportsLastInsertID() {
+		var id int64
+		err := b.queryRow(ctx, query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	res, err := b.exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// translate maps driver errors onto the store sentinel errors.
+func (b base) translate(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	}
+	if b.dialect.IsDuplicate(err) {
+		return store.ErrDuplicate
+	}
+	return err
+}
+
+// checkAffected reports store.ErrNotFound when an update touched no rows.
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
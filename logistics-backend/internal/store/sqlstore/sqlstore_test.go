@@ -0,0 +1,62 @@
+package sqlstore_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"logistics-backend/internal/config"
+	"logistics-backend/internal/database"
+	"logistics-backend/internal/migrations"
+	"logistics-backend/internal/store"
+	"logistics-backend/internal/store/sqlstore"
+)
+
+func TestNestedTxRollsBackAlone(t *testing.T) {
+	ctx := context.Background()
+	db, dialect, err := database.Open(&config.Config{
+		DBDriver: "sqlite",
+		DBPath:   filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m, err := migrations.New(db, dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	stores := sqlstore.New(db, dialect)
+
+	failed := errors.New("failed")
+	err = stores.Tx.WithinTx(ctx, func(tx *store.Stores) error {
+		if err := tx.Warehouses.Create(ctx, &store.Warehouse{Name: "Delhi Hub", Code: "DEL"}); err != nil {
+			return err
+		}
+		err := tx.Tx.WithinTx(ctx, func(tx *store.Stores) error {
+			if err := tx.Warehouses.Create(ctx, &store.Warehouse{Name: "Mumbai Hub", Code: "BOM"}); err != nil {
+				return err
+			}
+			// A savepoint inside a savepoint.
+			return tx.Tx.WithinTx(ctx, func(*store.Stores) error { return failed })
+		})
+		if !errors.Is(err, failed) {
+			t.Fatalf("nested WithinTx returned %v, want %v", err, failed)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	warehouses, err := stores.Warehouses.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warehouses) != 1 || warehouses[0].Code != "DEL" {
+		t.Fatalf("warehouses %+v, want DEL only", warehouses)
+	}
+}
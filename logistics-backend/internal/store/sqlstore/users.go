@@ -0,0 +1,74 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"logistics-backend/internal/store"
+)
+
+type userStore struct {
+	base
+}
+
+func (s *userStore) Create(ctx context.Context, u *store.User) error {
+	id, err := s.insert(ctx,
+		"INSERT INTO users (name, email, password_hash, role) VALUES (?, ?, ?, ?)",
+		u.Name, u.Email, u.PasswordHash, u.Role,
+	)
+	if err != nil {
+		return s.translate(err)
+	}
+	u.ID = id
+	return nil
+}
+
+func (s *userStore) GetByID(ctx context.Context, id int64) (*store.User, error) {
+	return s.getOne(ctx, "SELECT id, name, email, password_hash, role FROM users WHERE id = ?", id)
+}
+
+func (s *userStore) GetByEmail(ctx context.Context, email string) (*store.User, error) {
+	return s.getOne(ctx, "SELECT id, name, email, password_hash, role FROM users WHERE email = ?", email)
+}
+
+func (s *userStore) getOne(ctx context.Context, query string, arg any) (*store.User, error) {
+	var u store.User
+	err := s.queryRow(ctx, query, arg).Scan(&u.ID, &u.Name, &u.Email, &u.PasswordHash, &u.Role)
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	return &u, nil
+}
+
+func (s *userStore) List(ctx context.Context, role string) ([]store.User, error) {
+	var rows *sql.Rows
+	var err error
+	if role == "" {
+		rows, err = s.query(ctx, "SELECT id, name, email, role FROM users")
+	} else {
+		rows, err = s.query(ctx, "SELECT id, name, email, role FROM users WHERE "+s.dialect.FoldEqual("role"), role)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []store.User
+	for rows.Next() {
+		var u store.User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Role); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (s *userStore) ExistsWithRole(ctx context.Context, id int64, role string) (bool, error) {
+	var exists bool
+	err := s.queryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM users WHERE id = ? AND "+s.dialect.FoldEqual("role")+")",
+		id, role,
+	).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,75 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"logistics-backend/internal/store"
+)
+
+type vehicleStore struct {
+	base
+}
+
+func scanVehicle(row rowScanner) (*store.Vehicle, error) {
+	var v store.Vehicle
+	var lastUpdate sql.NullTime
+	if err := row.Scan(&v.ID, &v.DriverID, &v.CurrentLat, &v.CurrentLng, &lastUpdate); err != nil {
+		return nil, err
+	}
+	v.LastUpdate = lastUpdate.Time
+	return &v, nil
+}
+
+func (s *vehicleStore) Create(ctx context.Context, v *store.Vehicle) error {
+	id, err := s.insert(ctx,
+		`INSERT INTO vehicles (driver_id, current_lat, current_lng) VALUES (?, ?, ?)`,
+		v.DriverID, v.CurrentLat, v.CurrentLng,
+	)
+	if err != nil {
+		return s.translate(err)
+	}
+	v.ID = id
+	return nil
+}
+
+func (s *vehicleStore) GetByDriverID(ctx context.Context, driverID int64) (*store.Vehicle, error) {
+	v, err := scanVehicle(s.queryRow(ctx,
+		`SELECT id, driver_id, current_lat, current_lng, last_update FROM vehicles WHERE driver_id = ?`,
+		driverID,
+	))
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	return v, nil
+}
+
+func (s *vehicleStore) List(ctx context.Context) ([]store.Vehicle, error) {
+	rows, err := s.query(ctx,
+		`SELECT id, driver_id, current_lat, current_lng, last_update FROM vehicles`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vehicles []store.Vehicle
+	for rows.Next() {
+		v, err := scanVehicle(rows)
+		if err != nil {
+			return nil, err
+		}
+		vehicles = append(vehicles, *v)
+	}
+	return vehicles, rows.Err()
+}
+
+func (s *vehicleStore) UpdatePosition(ctx context.Context, id int64, lat, lng float64) error {
+	res, err := s.exec(ctx,
+		`UPDATE vehicles SET current_lat = ?, current_lng = ?, last_update = `+s.dialect.Now()+` WHERE id = ?`,
+		lat, lng, id,
+	)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
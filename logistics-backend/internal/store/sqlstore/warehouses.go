@@ -0,0 +1,64 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"logistics-backend/internal/store"
+)
+
+type warehouseStore struct {
+	base
+}
+
+func (s *warehouseStore) Create(ctx context.Context, w *store.Warehouse) error {
+	id, err := s.insert(ctx,
+		"INSERT INTO warehouses (name, code, latitude, longitude) VALUES (?, ?, ?, ?)",
+		w.Name, nullString(w.Code), w.Latitude, w.Longitude,
+	)
+	if err != nil {
+		return s.translate(err)
+	}
+	w.ID = id
+	return nil
+}
+
+const warehouseColumns = "id, name, code, latitude, longitude"
+
+func scanWarehouse(row rowScanner) (*store.Warehouse, error) {
+	var w store.Warehouse
+	var code sql.NullString
+	if err := row.Scan(&w.ID, &w.Name, &code, &w.Latitude, &w.Longitude); err != nil {
+		return nil, err
+	}
+	w.Code = code.String
+	return &w, nil
+}
+
+func (s *warehouseStore) GetByID(ctx context.Context, id int64) (*store.Warehouse, error) {
+	w, err := scanWarehouse(s.queryRow(ctx,
+		"SELECT "+warehouseColumns+" FROM warehouses WHERE id = ?", id,
+	))
+	if err != nil {
+		return nil, s.translate(err)
+	}
+	return w, nil
+}
+
+func (s *warehouseStore) List(ctx context.Context) ([]store.Warehouse, error) {
+	rows, err := s.query(ctx, "SELECT "+warehouseColumns+" FROM warehouses")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var warehouses []store.Warehouse
+	for rows.Next() {
+		w, err := scanWarehouse(rows)
+		if err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, *w)
+	}
+	return warehouses, rows.Err()
+}
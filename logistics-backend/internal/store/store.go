@@ -0,0 +1,381 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound  = errors.New("store: not found")
+	ErrDuplicate = errors.New("store: duplicate key")
+	// ErrConflict means a conditional update found the row changed since it
+	// was read.
+	ErrConflict = errors.New("store: conflicting update")
+)
+
+type User struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	PasswordHash string `json:"-"`
+	Role         string `json:"role"`
+}
+
+type Warehouse struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Code is the short hub code used in tracking numbers, such as "DEL".
+	Code      string  `json:"code,omitempty"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type Shipment struct {
+	ID                int64  `json:"id"`
+	TrackingNumber    string `json:"tracking_number"`
+	OriginWarehouseID int64  `json:"origin_warehouse_id"`
+	// PickupAddress, with its coordinates when known, is where a shipment
+	// that does not start at its origin warehouse is collected, such as a
+	// return from a customer.
+	PickupAddress        string   `json:"pickup_address,omitempty"`
+	PickupLatitude       *float64 `json:"pickup_latitude,omitempty"`
+	PickupLongitude      *float64 `json:"pickup_longitude,omitempty"`
+	DestinationAddress   string   `json:"destination_address"`
+	DestinationLatitude  *float64 `json:"destination_latitude,omitempty"`
+	DestinationLongitude *float64 `json:"destination_longitude,omitempty"`
+	// NeedsManualPin flags a shipment whose destination address could not be
+	// geocoded, until someone places its coordinates by hand.
+	NeedsManualPin bool `json:"needs_manual_pin"`
+	// ServiceLevel is the speed of service the shipment was booked at.
+	// Price is what it is charged, in Currency, for ChargeableWeightKg. It
+	// is fixed when the shipment is booked, or once its destination is
+	// placed if that was not known then.
+	ServiceLevel       string   `json:"service_level,omitempty"`
+	ChargeableWeightKg *float64 `json:"chargeable_weight_kg,omitempty"`
+	Price              *float64 `json:"price,omitempty"`
+	Currency           string   `json:"currency,omitempty"`
+	CustomerID         int64    `json:"customer_id"`
+	DriverID           *int64   `json:"driver_id,omitempty"`
+	Status             string   `json:"status"`
+	// Version counts the writes to the shipment, starting at 1. It is served
+	// as the shipment's ETag.
+	Version   int64      `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Piece is one parcel of a shipment. Its barcode is the shipment's
+// tracking number followed by the piece number, and it moves through the
+// same statuses as a shipment.
+type Piece struct {
+	ID            int64      `json:"id"`
+	ShipmentID    int64      `json:"shipment_id"`
+	Number        int        `json:"piece_number"`
+	Barcode       string     `json:"barcode"`
+	Description   string     `json:"description,omitempty"`
+	WeightKg      *float64   `json:"weight_kg,omitempty"`
+	LengthCm      *float64   `json:"length_cm,omitempty"`
+	WidthCm       *float64   `json:"width_cm,omitempty"`
+	HeightCm      *float64   `json:"height_cm,omitempty"`
+	DeclaredValue *float64   `json:"declared_value,omitempty"`
+	Status        string     `json:"status"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+}
+
+type ShipmentEvent struct {
+	ID         int64    `json:"id"`
+	ShipmentID int64    `json:"shipment_id"`
+	PieceID    *int64   `json:"piece_id,omitempty"`
+	Status     string   `json:"status"`
+	Latitude   *float64 `json:"latitude,omitempty"`
+	Longitude  *float64 `json:"longitude,omitempty"`
+	ActorID    *int64   `json:"actor_id,omitempty"`
+	ActorRole  string   `json:"actor_role,omitempty"`
+	Reason     string   `json:"reason,omitempty"`
+	// ScanType is set on events recorded from a barcode scan, together
+	// with ScannedAt, the time the scanning device reported.
+	ScanType  string     `json:"scan_type,omitempty"`
+	ScannedAt *time.Time `json:"scanned_at,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+}
+
+// DeliveryProof is what a driver captured when handing a shipment over. The
+// images live in a blob store under the given keys.
+type DeliveryProof struct {
+	ID            int64     `json:"id"`
+	ShipmentID    int64     `json:"shipment_id"`
+	EventID       int64     `json:"event_id"`
+	RecipientName string    `json:"recipient_name"`
+	SignatureKey  string    `json:"-"`
+	SignatureType string    `json:"signature_content_type"`
+	PhotoKey      string    `json:"-"`
+	PhotoType     string    `json:"photo_content_type,omitempty"`
+	Latitude      float64   `json:"latitude"`
+	Longitude     float64   `json:"longitude"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// DeliveryAttempt is a failed attempt to hand a shipment over. Attempts are
+// numbered from 1 per shipment.
+type DeliveryAttempt struct {
+	ID         int64  `json:"id"`
+	ShipmentID int64  `json:"shipment_id"`
+	Number     int    `json:"attempt_number"`
+	EventID    int64  `json:"event_id"`
+	Reason     string `json:"reason"`
+	Notes      string `json:"notes,omitempty"`
+	// NextAttemptOn is the day the delivery is tried again, at midnight
+	// UTC. It is nil when the attempt sent the shipment back to origin.
+	NextAttemptOn *time.Time `json:"next_attempt_on,omitempty"`
+	Latitude      *float64   `json:"latitude,omitempty"`
+	Longitude     *float64   `json:"longitude,omitempty"`
+	ActorID       *int64     `json:"actor_id,omitempty"`
+	ActorRole     string     `json:"actor_role,omitempty"`
+	AttemptedAt   time.Time  `json:"attempted_at"`
+}
+
+// ShipmentReturn links a return shipment to the shipment it sends back.
+// The return is picked up where the original was delivered to and travels
+// to the original's origin warehouse.
+type ShipmentReturn struct {
+	ID                 int64  `json:"id"`
+	RMANumber          string `json:"rma_number"`
+	OriginalShipmentID int64  `json:"original_shipment_id"`
+	ReturnShipmentID   int64  `json:"return_shipment_id"`
+	Reason             string `json:"reason"`
+	Notes              string `json:"notes,omitempty"`
+	// PickupAddress is where the return is collected. It is empty when
+	// the original failed delivery and never left the carrier.
+	PickupAddress   string   `json:"pickup_address,omitempty"`
+	PickupLatitude  *float64 `json:"pickup_latitude,omitempty"`
+	PickupLongitude *float64 `json:"pickup_longitude,omitempty"`
+	// RequestedBy is the user who opened the return.
+	RequestedBy   *int64    `json:"requested_by,omitempty"`
+	RequestedRole string    `json:"requested_role,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ShipmentChange records one field of a shipment edited through the API,
+// with its value before and after the edit. A nil value was unset.
+type ShipmentChange struct {
+	ID         int64 `json:"id"`
+	ShipmentID int64 `json:"shipment_id"`
+	// Version is the shipment version the edit produced.
+	Version   int64     `json:"version"`
+	Field     string    `json:"field"`
+	OldValue  *string   `json:"old_value"`
+	NewValue  *string   `json:"new_value"`
+	ActorID   *int64    `json:"actor_id,omitempty"`
+	ActorRole string    `json:"actor_role,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type Vehicle struct {
+	ID         int64     `json:"id"`
+	DriverID   int64     `json:"driver_id"`
+	CurrentLat float64   `json:"current_lat"`
+	CurrentLng float64   `json:"current_lng"`
+	LastUpdate time.Time `json:"last_update"`
+}
+
+// ShipmentSummary is a shipment with its most recent event, assigned driver
+// and number of pieces, as exported in reports.
+type ShipmentSummary struct {
+	Shipment
+	LatestEvent *ShipmentEvent
+	Driver      *User
+	PieceCount  int
+}
+
+// ShipmentPosition is the most recent located event of a shipment.
+type ShipmentPosition struct {
+	ShipmentID     int64     `json:"id"`
+	TrackingNumber string    `json:"tracking_number"`
+	Lat            float64   `json:"lat"`
+	Lng            float64   `json:"lng"`
+	Timestamp      time.Time `json:"-"`
+}
+
+// ShipmentFilter narrows shipment listings. Zero values match everything.
+type ShipmentFilter struct {
+	CustomerID        int64
+	DriverID          int64
+	OriginWarehouseID int64
+	Statuses          []string
+	// CreatedFrom is inclusive and CreatedTo exclusive.
+	CreatedFrom, CreatedTo time.Time
+	// TrackingPrefix matches tracking numbers starting with it. It must only
+	// hold upper-case letters, digits and dashes.
+	TrackingPrefix string
+	// NeedsManualPin keeps only shipments awaiting a manual pin.
+	NeedsManualPin bool
+}
+
+// Sort keys for shipment listings.
+const (
+	SortByID             = "id"
+	SortByCreatedAt      = "created_at"
+	SortByTrackingNumber = "tracking_number"
+	SortByStatus         = "status"
+)
+
+// ShipmentQuery is a filtered, sorted page of shipments. Ties on the sort
+// key are broken by ID in the same direction.
+type ShipmentQuery struct {
+	ShipmentFilter
+	// SortBy is one of the SortBy constants; empty sorts by ID.
+	SortBy string
+	Desc   bool
+	// After continues the listing after this shipment. Only its ID and the
+	// field named by SortBy are read.
+	After *Shipment
+	// Limit caps the number of shipments returned; zero means no limit.
+	Limit int
+}
+
+// EventFilter selects events of one shipment. Zero values match everything.
+type EventFilter struct {
+	ShipmentID int64
+	PieceID    int64
+	Statuses   []string
+	// From is inclusive and To exclusive.
+	From, To time.Time
+	// AfterID continues the timeline after the event with this ID.
+	AfterID int64
+	// Limit caps the number of events returned; zero means no limit.
+	Limit int
+}
+
+type UserStore interface {
+	Create(ctx context.Context, u *User) error
+	GetByID(ctx context.Context, id int64) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	// List returns users with the given role, or every user if role is empty.
+	List(ctx context.Context, role string) ([]User, error)
+	ExistsWithRole(ctx context.Context, id int64, role string) (bool, error)
+}
+
+type WarehouseStore interface {
+	Create(ctx context.Context, w *Warehouse) error
+	GetByID(ctx context.Context, id int64) (*Warehouse, error)
+	List(ctx context.Context) ([]Warehouse, error)
+}
+
+type ShipmentStore interface {
+	Create(ctx context.Context, s *Shipment) error
+	GetByID(ctx context.Context, id int64) (*Shipment, error)
+	GetByTrackingNumber(ctx context.Context, trackingNumber string) (*Shipment, error)
+	List(ctx context.Context, q ShipmentQuery) ([]Shipment, error)
+	Count(ctx context.Context, f ShipmentFilter) (int, error)
+	// Each calls fn with the summary of every shipment matching q, reading
+	// rows as fn consumes them. fn must not use the stores: on SQLite the
+	// only connection stays busy until Each returns.
+	Each(ctx context.Context, q ShipmentQuery, fn func(*ShipmentSummary) error) error
+	// UpdateStatus moves a shipment from one status to another, failing with
+	// ErrConflict if its current status is no longer from.
+	UpdateStatus(ctx context.Context, id int64, from, to string) error
+	AssignDriver(ctx context.Context, id, driverID int64) error
+	// Update saves the origin warehouse, destination, pricing and customer
+	// of sh if the stored shipment is still at version, and sets sh.Version
+	// to the new version. It fails with ErrConflict if the version moved on.
+	Update(ctx context.Context, sh *Shipment, version int64) error
+}
+
+type ChangeStore interface {
+	Create(ctx context.Context, c *ShipmentChange) error
+	// List returns a shipment's changes oldest first.
+	List(ctx context.Context, shipmentID int64) ([]ShipmentChange, error)
+}
+
+type ProofStore interface {
+	// Create fails with ErrDuplicate if the shipment already has a proof.
+	Create(ctx context.Context, p *DeliveryProof) error
+	GetByShipment(ctx context.Context, shipmentID int64) (*DeliveryProof, error)
+}
+
+type AttemptStore interface {
+	// Create fails with ErrDuplicate if the shipment already has an attempt
+	// with the same number.
+	Create(ctx context.Context, a *DeliveryAttempt) error
+	// ListByShipment returns a shipment's attempts in order.
+	ListByShipment(ctx context.Context, shipmentID int64) ([]DeliveryAttempt, error)
+	// Latest returns the last attempt of every shipment matching f that has
+	// one, ordered by shipment ID.
+	Latest(ctx context.Context, f ShipmentFilter) ([]DeliveryAttempt, error)
+}
+
+type ReturnStore interface {
+	// Create fails with ErrDuplicate if the original shipment already has a
+	// return or the RMA number is taken.
+	Create(ctx context.Context, r *ShipmentReturn) error
+	// GetByShipment finds the return a shipment is either the original or
+	// the return shipment of.
+	GetByShipment(ctx context.Context, shipmentID int64) (*ShipmentReturn, error)
+	GetByRMA(ctx context.Context, rmaNumber string) (*ShipmentReturn, error)
+}
+
+type PieceStore interface {
+	Create(ctx context.Context, p *Piece) error
+	GetByBarcode(ctx context.Context, barcode string) (*Piece, error)
+	// ListByShipment returns a shipment's pieces ordered by piece number.
+	ListByShipment(ctx context.Context, shipmentID int64) ([]Piece, error)
+	// UpdateStatus moves a piece from one status to another, failing with
+	// ErrConflict if its current status is no longer from.
+	UpdateStatus(ctx context.Context, id int64, from, to string) error
+	// UpdateStatuses moves every piece of a shipment that is in one of the
+	// from statuses to to.
+	UpdateStatuses(ctx context.Context, shipmentID int64, from []string, to string) error
+}
+
+type EventStore interface {
+	Create(ctx context.Context, e *ShipmentEvent) error
+	// List returns the events matching f oldest first, ordered by timestamp
+	// and then ID.
+	List(ctx context.Context, f EventFilter) ([]ShipmentEvent, error)
+	// Latest returns the most recent event with coordinates for a shipment.
+	Latest(ctx context.Context, shipmentID int64) (*ShipmentEvent, error)
+	// LatestPositions returns the latest located event of every shipment
+	// matching f, ordered by shipment ID.
+	LatestPositions(ctx context.Context, f ShipmentFilter) ([]ShipmentPosition, error)
+	// RecentLocated returns up to n of the latest located events of every
+	// shipment matching f, ordered by shipment ID and then oldest first.
+	RecentLocated(ctx context.Context, f ShipmentFilter, n int) ([]ShipmentEvent, error)
+}
+
+type VehicleStore interface {
+	Create(ctx context.Context, v *Vehicle) error
+	GetByDriverID(ctx context.Context, driverID int64) (*Vehicle, error)
+	List(ctx context.Context) ([]Vehicle, error)
+	UpdatePosition(ctx context.Context, id int64, lat, lng float64) error
+}
+
+// SequenceStore hands out numbers from named counters that survive
+// restarts. Each counter starts at 1.
+type SequenceStore interface {
+	Next(ctx context.Context, name string) (int64, error)
+}
+
+// Transactor runs fn against a copy of the stores bound to one transaction.
+// The transaction commits if fn returns nil and rolls back otherwise.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(tx *Stores) error) error
+}
+
+// Stores bundles one implementation of every store so it can be handed to
+// the handlers as a unit.
+type Stores struct {
+	Users      UserStore
+	Warehouses WarehouseStore
+	Shipments  ShipmentStore
+	Pieces     PieceStore
+	Events     EventStore
+	Changes    ChangeStore
+	Proofs     ProofStore
+	Attempts   AttemptStore
+	Returns    ReturnStore
+	Vehicles   VehicleStore
+	Sequences  SequenceStore
+	Tx         Transactor
+}
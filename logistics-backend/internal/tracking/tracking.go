@@ -0,0 +1,329 @@
+// Package tracking generates and validates shipment tracking numbers.
+//
+// A number is laid out by a template such as "LG{warehouse}{seq:8}{check}":
+// literal text, the origin warehouse code, a zero-padded sequence number and
+// a check digit computed over every other letter and digit in the number.
+package tracking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"logistics-backend/internal/store"
+)
+
+var (
+	ErrMalformed  = errors.New("tracking number does not match the expected format")
+	ErrCheckDigit = errors.New("tracking number check digit is wrong")
+)
+
+// Check digit schemes.
+const (
+	// Mod10 is the Luhn algorithm applied after expanding letters to two
+	// digits (A=10 … Z=35), as used by ISINs.
+	Mod10 = "mod10"
+	// Mod11 weights characters 2 to 7 from the right and writes a
+	// remainder of 10 as "X", as ISBN-10 does.
+	Mod11 = "mod11"
+	None  = "none"
+)
+
+type tokenKind int
+
+const (
+	literal tokenKind = iota
+	warehouse
+	sequence
+	check
+)
+
+type token struct {
+	kind  tokenKind
+	text  string // literal text
+	width int    // sequence padding
+}
+
+// Format is a parsed tracking number template.
+type Format struct {
+	tokens  []token
+	scheme  string
+	pattern *regexp.Regexp
+	// legacy matches the numbers of shipments booked before numbers were
+	// generated; see WithLegacy.
+	legacy *regexp.Regexp
+}
+
+var placeholder = regexp.MustCompile(`\{(warehouse|seq(?::(\d+))?|check)\}`)
+
+var literalText = regexp.MustCompile(`^[A-Z0-9-]*$`)
+
+// ParseFormat parses a template built from literal upper-case letters,
+// digits and dashes plus the placeholders {warehouse}, {seq} or {seq:N} and
+// {check}. It needs exactly one sequence, and a {check} placeholder exactly
+// when scheme is not None.
+func ParseFormat(layout, scheme string) (*Format, error) {
+	switch scheme {
+	case Mod10, Mod11, None:
+	default:
+		return nil, fmt.Errorf("tracking: unknown check digit scheme %q", scheme)
+	}
+
+	f := &Format{scheme: scheme}
+	var counts [4]int
+	addLiteral := func(s string) error {
+		if s == "" {
+			return nil
+		}
+		if !literalText.MatchString(s) {
+			return fmt.Errorf("tracking: literal %q may only hold upper-case letters, digits and dashes", s)
+		}
+		f.tokens = append(f.tokens, token{kind: literal, text: s})
+		return nil
+	}
+
+	rest := layout
+	for _, m := range placeholder.FindAllStringSubmatchIndex(layout, -1) {
+		if err := addLiteral(layout[len(layout)-len(rest) : m[0]]); err != nil {
+			return nil, err
+		}
+		rest = layout[m[1]:]
+
+		name := layout[m[2]:m[3]]
+		switch {
+		case name == "warehouse":
+			f.tokens = append(f.tokens, token{kind: warehouse})
+			counts[warehouse]++
+		case name == "check":
+			f.tokens = append(f.tokens, token{kind: check})
+			counts[check]++
+		default:
+			width := 8
+			if m[4] >= 0 {
+				width, _ = strconv.Atoi(layout[m[4]:m[5]])
+			}
+			if width < 1 || width > 18 {
+				return nil, fmt.Errorf("tracking: sequence width %d is not between 1 and 18", width)
+			}
+			f.tokens = append(f.tokens, token{kind: sequence, width: width})
+			counts[sequence]++
+		}
+	}
+	if err := addLiteral(rest); err != nil {
+		return nil, err
+	}
+
+	if counts[sequence] != 1 {
+		return nil, errors.New("tracking: format needs exactly one {seq} placeholder")
+	}
+	if counts[warehouse] > 1 {
+		return nil, errors.New("tracking: format may hold at most one {warehouse} placeholder")
+	}
+	if scheme == None && counts[check] != 0 {
+		return nil, errors.New("tracking: {check} needs a check digit scheme")
+	}
+	if scheme != None && counts[check] != 1 {
+		return nil, fmt.Errorf("tracking: scheme %s needs exactly one {check} placeholder", scheme)
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for _, t := range f.tokens {
+		switch t.kind {
+		case literal:
+			pattern.WriteString(regexp.QuoteMeta(t.text))
+		case warehouse:
+			pattern.WriteString("[A-Z0-9]+")
+		case sequence:
+			// Sequences outgrow their padding rather than wrap around.
+			pattern.WriteString(fmt.Sprintf("[0-9]{%d,}", t.width))
+		case check:
+			if scheme == Mod11 {
+				pattern.WriteString("([0-9X])")
+			} else {
+				pattern.WriteString("([0-9])")
+			}
+		}
+	}
+	pattern.WriteString("$")
+	f.pattern = regexp.MustCompile(pattern.String())
+	return f, nil
+}
+
+// scope is the part of a number that stays fixed for one warehouse. Each
+// scope has its own sequence.
+func (f *Format) scope(warehouseCode string) string {
+	var b strings.Builder
+	for _, t := range f.tokens {
+		switch t.kind {
+		case literal:
+			b.WriteString(t.text)
+		case warehouse:
+			b.WriteString(warehouseCode)
+		}
+	}
+	return b.String()
+}
+
+// Number renders the tracking number for a warehouse code and sequence.
+func (f *Format) Number(warehouseCode string, seq int64) string {
+	var b strings.Builder
+	checkAt := -1
+	for _, t := range f.tokens {
+		switch t.kind {
+		case literal:
+			b.WriteString(t.text)
+		case warehouse:
+			b.WriteString(warehouseCode)
+		case sequence:
+			fmt.Fprintf(&b, "%0*d", t.width, seq)
+		case check:
+			checkAt = b.Len()
+		}
+	}
+	number := b.String()
+	if checkAt < 0 {
+		return number
+	}
+	return number[:checkAt] + string(checkDigit(f.scheme, number)) + number[checkAt:]
+}
+
+// Normalize upper-cases a number and trims surrounding space, so numbers
+// typed by people compare equal to the generated ones.
+func Normalize(number string) string {
+	return strings.ToUpper(strings.TrimSpace(number))
+}
+
+// Validate reports whether a normalized number matches the format and, when
+// the format has one, carries the right check digit.
+func (f *Format) Validate(number string) error {
+	m := f.pattern.FindStringSubmatchIndex(number)
+	if m == nil {
+		return ErrMalformed
+	}
+	if f.scheme == None {
+		return nil
+	}
+	got := number[m[2]]
+	if checkDigit(f.scheme, number[:m[2]]+number[m[3]:]) != got {
+		return ErrCheckDigit
+	}
+	return nil
+}
+
+// WithLegacy returns a copy of f whose Trackable also accepts numbers
+// matching pattern in full, such as `TRK-[0-9]+`. They are the numbers
+// shipments were booked under before numbers were generated. An empty
+// pattern accepts none.
+func (f *Format) WithLegacy(pattern string) (*Format, error) {
+	g := *f
+	g.legacy = nil
+	if pattern == "" {
+		return &g, nil
+	}
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("tracking: legacy pattern: %w", err)
+	}
+	g.legacy = re
+	return &g, nil
+}
+
+// Trackable reports whether a normalized number may belong to a shipment:
+// it either passes Validate or is a legacy number. Other numbers need no
+// lookup to know they match nothing.
+func (f *Format) Trackable(number string) error {
+	err := f.Validate(number)
+	if err != nil && f.legacy != nil && f.legacy.MatchString(number) {
+		return nil
+	}
+	return err
+}
+
+// checkDigit computes the check character over the letters and digits of s.
+func checkDigit(scheme, s string) byte {
+	var values []int
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			values = append(values, int(r-'0'))
+		case r >= 'A' && r <= 'Z':
+			values = append(values, int(r-'A')+10)
+		}
+	}
+
+	if scheme == Mod11 {
+		sum := 0
+		for i := range values {
+			weight := 2 + i%6
+			sum += values[len(values)-1-i] * weight
+		}
+		switch d := (11 - sum%11) % 11; d {
+		case 10:
+			return 'X'
+		default:
+			return byte('0' + d)
+		}
+	}
+
+	// Luhn over the decimal expansion, doubling every second digit from the
+	// right starting with the rightmost.
+	var digits []int
+	for _, v := range values {
+		if v >= 10 {
+			digits = append(digits, v/10)
+		}
+		digits = append(digits, v%10)
+	}
+	sum := 0
+	for i := range digits {
+		d := digits[len(digits)-1-i]
+		if i%2 == 0 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// Generator hands out tracking numbers backed by a persistent sequence per
+// warehouse.
+type Generator struct {
+	format    *Format
+	sequences store.SequenceStore
+}
+
+func NewGenerator(format *Format, sequences store.SequenceStore) *Generator {
+	return &Generator{format: format, sequences: sequences}
+}
+
+// Format returns the layout numbers are generated and validated against.
+func (g *Generator) Format() *Format {
+	return g.format
+}
+
+// Next returns a fresh number for a shipment leaving the given warehouse.
+// Numbers are unique as long as nothing else writes into the same range;
+// callers should still retry on a duplicate key.
+func (g *Generator) Next(ctx context.Context, w *store.Warehouse) (string, error) {
+	code := WarehouseCode(w)
+	seq, err := g.sequences.Next(ctx, "tracking:"+g.format.scope(code))
+	if err != nil {
+		return "", err
+	}
+	return g.format.Number(code, seq), nil
+}
+
+// WarehouseCode is the warehouse's code, or its zero-padded ID for hubs
+// created before codes existed.
+func WarehouseCode(w *store.Warehouse) string {
+	if w.Code != "" {
+		return w.Code
+	}
+	return fmt.Sprintf("%03d", w.ID)
+}
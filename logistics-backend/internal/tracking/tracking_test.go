@@ -0,0 +1,162 @@
+package tracking
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckDigit(t *testing.T) {
+	tests := []struct {
+		scheme, payload string
+		want            byte
+	}{
+		// Real ISINs, whose check digit is this Luhn variant.
+		{Mod10, "US037833100", '5'},
+		{Mod10, "AU0000XVGZA", '3'},
+		{Mod10, "GB000263494", '6'},
+		{Mod10, "LGDEL00000001", '0'},
+		// Weights 2, 3, 4, 5, 6, 7, 2, … from the right, sum mod 11.
+		{Mod11, "LGDEL00000001", '8'},
+		{Mod11, "LGBOM00000042", '7'},
+		{Mod11, "LGDEL00000005", '0'},
+		{Mod11, "LGDEL00000014", 'X'},
+	}
+	for _, tt := range tests {
+		if got := checkDigit(tt.scheme, tt.payload); got != tt.want {
+			t.Errorf("%s check digit of %s = %c, want %c", tt.scheme, tt.payload, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		scheme string
+		seq    int64
+		// number is what Number should render.
+		number string
+	}{
+		{Mod10, 1, "LGDEL000000010"},
+		{Mod11, 1, "LGDEL000000018"},
+		{Mod11, 14, "LGDEL00000014X"},
+		{None, 1, "LGDEL00000001"},
+	}
+	for _, tt := range tests {
+		layout := "LG{warehouse}{seq:8}{check}"
+		if tt.scheme == None {
+			layout = "LG{warehouse}{seq:8}"
+		}
+		f, err := ParseFormat(layout, tt.scheme)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.Number("DEL", tt.seq); got != tt.number {
+			t.Fatalf("%s number %d = %s, want %s", tt.scheme, tt.seq, got, tt.number)
+		}
+		if err := f.Validate(tt.number); err != nil {
+			t.Errorf("%s: Validate(%s) = %v", tt.scheme, tt.number, err)
+		}
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	mod10, err := ParseFormat("LG{warehouse}{seq:8}{check}", Mod10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod11, err := ParseFormat("LG{warehouse}{seq:8}{check}", Mod11)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		format *Format
+		number string
+		want   error
+	}{
+		// LGDEL000000127 and LGDEL000000123 are the numbers for sequence 12.
+		{"mod10 transposition", mod10, "LGDEL000000217", ErrCheckDigit},
+		{"mod10 single digit", mod10, "LGDEL000000137", ErrCheckDigit},
+		{"mod11 transposition", mod11, "LGDEL000000213", ErrCheckDigit},
+		{"mod11 single digit", mod11, "LGDEL000000133", ErrCheckDigit},
+		{"mod11 wrong X", mod11, "LGDEL00000001X", ErrCheckDigit},
+		{"mod10 X", mod10, "LGDEL00000001X", ErrMalformed},
+		{"short sequence", mod11, "LGDEL00000018", ErrMalformed},
+		{"wrong prefix", mod11, "XXDEL000000018", ErrMalformed},
+		{"lower case", mod11, "lgdel000000018", ErrMalformed},
+		{"legacy", mod11, "TRK-1001", ErrMalformed},
+	}
+	if n := mod10.Number("DEL", 12); n != "LGDEL000000127" {
+		t.Fatalf("mod10 number 12 = %s", n)
+	}
+	if n := mod11.Number("DEL", 12); n != "LGDEL000000123" {
+		t.Fatalf("mod11 number 12 = %s", n)
+	}
+	for _, tt := range tests {
+		if err := tt.format.Validate(tt.number); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Validate(%s) = %v, want %v", tt.name, tt.number, err, tt.want)
+		}
+	}
+}
+
+func TestParseFormatRejects(t *testing.T) {
+	tests := []struct {
+		layout, scheme string
+	}{
+		{"LG{warehouse}{seq}{check}", "luhn"},
+		{"LG{warehouse}{check}", Mod11},
+		{"LG{seq}{seq}{check}", Mod11},
+		{"{warehouse}{warehouse}{seq}{check}", Mod11},
+		{"LG{warehouse}{seq}", Mod11},
+		{"LG{seq}{check}{check}", Mod11},
+		{"LG{seq}{check}", None},
+		{"LG{seq:0}{check}", Mod11},
+		{"LG{seq:19}{check}", Mod11},
+		{"lg{seq}{check}", Mod11},
+		{"LG_{seq}{check}", Mod11},
+	}
+	for _, tt := range tests {
+		if _, err := ParseFormat(tt.layout, tt.scheme); err == nil {
+			t.Errorf("ParseFormat(%q, %q) succeeded", tt.layout, tt.scheme)
+		}
+	}
+}
+
+func TestTrackable(t *testing.T) {
+	f, err := ParseFormat("LG{warehouse}{seq:8}{check}", Mod11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Trackable("TRK-1001"); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("without a legacy pattern: %v, want ErrMalformed", err)
+	}
+	legacy, err := f.WithLegacy("TRK-[0-9]+")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		number string
+		want   error
+	}{
+		{"LGDEL000000123", nil},
+		{"TRK-1001", nil},
+		{"LGDEL000000133", ErrCheckDigit},
+		// The pattern must match the whole number.
+		{"TRK-1001-A", ErrMalformed},
+		{"XTRK-1001", ErrMalformed},
+		{"TRK-", ErrMalformed},
+	}
+	for _, tt := range tests {
+		if err := legacy.Trackable(tt.number); !errors.Is(err, tt.want) {
+			t.Errorf("Trackable(%s) = %v, want %v", tt.number, err, tt.want)
+		}
+	}
+	// The original format is left as it was.
+	if err := f.Trackable("TRK-1001"); err == nil {
+		t.Fatal("WithLegacy changed the format it was called on")
+	}
+	if _, err := f.WithLegacy("TRK-[0-9"); err == nil {
+		t.Fatal("WithLegacy accepted an invalid pattern")
+	}
+}